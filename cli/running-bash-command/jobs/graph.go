@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+)
+
+type graph struct {
+	jobs       []Job
+	byName     map[string]Job
+	dependents map[string][]string // in the order of jobs
+	levels     map[string]int
+}
+
+func newGraph(jobs []Job) (*graph, error) {
+	g := &graph{jobs: jobs, byName: map[string]Job{}, dependents: map[string][]string{}, levels: map[string]int{}}
+
+	for _, job := range jobs {
+		if job.Name == "" {
+			return nil, fmt.Errorf("jobs: a job has no name")
+		}
+		if _, ok := g.byName[job.Name]; ok {
+			return nil, fmt.Errorf("jobs: duplicate job %q", job.Name)
+		}
+		g.byName[job.Name] = job
+	}
+
+	for _, job := range jobs {
+		for _, dep := range job.DependsOn {
+			if _, ok := g.byName[dep]; !ok {
+				return nil, fmt.Errorf("jobs: %q depends on unknown job %q", job.Name, dep)
+			}
+			g.dependents[dep] = append(g.dependents[dep], job.Name)
+		}
+	}
+
+	// depth first search, computing the levels and detecting cycles
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			return fmt.Errorf("jobs: dependency cycle %v", strings.Join(append(path[start:], name), " -> "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		level := 0
+		for _, dep := range g.byName[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+			level = max(level, g.levels[dep]+1)
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		g.levels[name] = level
+		return nil
+	}
+
+	for _, job := range jobs {
+		if err := visit(job.Name); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
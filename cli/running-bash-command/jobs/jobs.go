@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"running-bash-command/runner"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Job is a named command that runs once all the jobs it depends on succeeded
+type Job struct {
+	Name      string
+	Command   runner.Command
+	DependsOn []string
+}
+
+type Options struct {
+	// Parallelism is the number of jobs running at once, runtime.NumCPU() if 0
+	Parallelism int
+	// FailFast makes the first failure cancel the running jobs and start no
+	// other. By default only the jobs that depend on a failed job are
+	// skipped, the others keep running, like make -k.
+	FailFast bool
+	// DryRun validates the graph and reports the order the jobs would run
+	// in, without running them
+	DryRun bool
+	// Output receives the output of the jobs as it is read, each line
+	// prefixed with the name of its job, unless the command has its own Tee
+	Output io.Writer
+}
+
+type Status string
+
+const (
+	Succeeded Status = "ok"
+	Failed    Status = "failed"
+	// Skipped jobs depend on a job that did not succeed
+	Skipped Status = "skipped"
+	// Canceled jobs were stopped or never started after a failure with
+	// FailFast, or the cancellation of the context
+	Canceled Status = "canceled"
+	// Planned jobs would run, in dry-run mode
+	Planned Status = "planned"
+)
+
+type JobResult struct {
+	Name    string
+	Command string
+	Status  Status
+	// Level is the position of the job in the graph: 0 for jobs without
+	// dependencies, otherwise one more than their deepest dependency
+	Level     int
+	DependsOn []string
+	// Result is nil when the command did not run
+	Result   *runner.Result
+	Err      error
+	Duration time.Duration
+}
+
+// Run runs the jobs in dependency order with bounded parallelism. The graph
+// is validated first: names must be unique, dependencies must exist and
+// must not form a cycle. The error is not nil if a job did not succeed.
+func Run(ctx context.Context, jobs []Job, opts Options) (*Summary, error) {
+	g, err := newGraph(jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Jobs: make([]JobResult, len(jobs))}
+	for i, job := range jobs {
+		summary.Jobs[i] = JobResult{Name: job.Name, Command: job.Command.String(), Level: g.levels[job.Name], DependsOn: job.DependsOn}
+	}
+	if opts.DryRun {
+		for i := range summary.Jobs {
+			summary.Jobs[i].Status = Planned
+		}
+		return summary, nil
+	}
+
+	start := time.Now()
+	s := &scheduler{graph: g, opts: opts, results: map[string]*JobResult{}}
+	for i := range summary.Jobs {
+		s.results[summary.Jobs[i].Name] = &summary.Jobs[i]
+	}
+	s.run(ctx)
+	summary.Duration = time.Since(start)
+
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	if failed := summary.failed(); len(failed) > 0 {
+		return summary, fmt.Errorf("jobs failed: %v", strings.Join(failed, ", "))
+	}
+	return summary, nil
+}
+
+type scheduler struct {
+	graph   *graph
+	opts    Options
+	results map[string]*JobResult
+	// remaining counts the dependencies of each job that did not succeed yet
+	remaining map[string]int
+	ready     []string
+	finished  int
+}
+
+func (s *scheduler) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parallelism := s.opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	s.remaining = map[string]int{}
+	for _, job := range s.graph.jobs {
+		s.remaining[job.Name] = len(job.DependsOn)
+		if len(job.DependsOn) == 0 {
+			s.ready = append(s.ready, job.Name)
+		}
+	}
+
+	done := make(chan *JobResult)
+	running := 0
+	stopped := false
+	for s.finished < len(s.graph.jobs) {
+		stopped = stopped || ctx.Err() != nil
+		for !stopped && running < parallelism && len(s.ready) > 0 {
+			job := s.graph.byName[s.ready[0]]
+			s.ready = s.ready[1:]
+			running++
+			go func() { done <- s.runJob(ctx, job) }()
+		}
+		if running == 0 {
+			// stopped, or nothing left can run
+			break
+		}
+
+		result := <-done
+		running--
+		s.finished++
+
+		if result.Status == Succeeded {
+			for _, dependent := range s.graph.dependents[result.Name] {
+				if s.remaining[dependent]--; s.remaining[dependent] == 0 && s.results[dependent].Status == "" {
+					s.ready = append(s.ready, dependent)
+				}
+			}
+			continue
+		}
+
+		s.skipDependents(result.Name)
+		if s.opts.FailFast && !stopped {
+			stopped = true
+			cancel()
+		}
+	}
+
+	for _, result := range s.results {
+		if result.Status == "" {
+			result.Status = Canceled
+		}
+	}
+}
+
+func (s *scheduler) skipDependents(name string) {
+	for _, dependent := range s.graph.dependents[name] {
+		if result := s.results[dependent]; result.Status == "" {
+			result.Status = Skipped
+			s.finished++
+			s.skipDependents(dependent)
+		}
+	}
+}
+
+func (s *scheduler) runJob(ctx context.Context, job Job) *JobResult {
+	result := s.results[job.Name]
+
+	cmd := job.Command
+	if s.opts.Output != nil && cmd.Tee == nil {
+		cmd.Tee = s.opts.Output
+		if cmd.Prefix == "" {
+			cmd.Prefix = "[" + job.Name + "] "
+		}
+	}
+
+	start := time.Now()
+	res, err := runner.Run(ctx, cmd)
+	result.Duration = time.Since(start)
+	result.Result, result.Err = res, err
+
+	switch {
+	case err == nil:
+		result.Status = Succeeded
+	case ctx.Err() != nil:
+		// killed after another job failed or the context was canceled
+		result.Status = Canceled
+	default:
+		result.Status = Failed
+	}
+	return result
+}
@@ -0,0 +1,214 @@
+//go:build unix
+
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"running-bash-command/runner"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sh(script string) runner.Command {
+	return runner.Command{Name: "sh", Args: []string{"-c", script}}
+}
+
+func statuses(summary *Summary) map[string]Status {
+	m := map[string]Status{}
+	for _, job := range summary.Jobs {
+		m[job.Name] = job.Status
+	}
+	return m
+}
+
+func TestRunOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) runner.Command {
+		c := sh("echo " + name)
+		c.OnLine = func(line runner.Line) {
+			mu.Lock()
+			order = append(order, line.Text)
+			mu.Unlock()
+		}
+		return c
+	}
+
+	// a diamond: a runs first, d last
+	summary, err := Run(context.Background(), []Job{
+		{Name: "d", Command: record("d"), DependsOn: []string{"b", "c"}},
+		{Name: "b", Command: record("b"), DependsOn: []string{"a"}},
+		{Name: "c", Command: record("c"), DependsOn: []string{"a"}},
+		{Name: "a", Command: record("a")},
+	}, Options{Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order) != 4 || order[0] != "a" || order[3] != "d" {
+		t.Errorf("order = %v", order)
+	}
+	for _, job := range summary.Jobs {
+		if job.Status != Succeeded {
+			t.Errorf("%v: status = %v", job.Name, job.Status)
+		}
+	}
+	if levels := []int{2, 1, 1, 0}; summary.Jobs[0].Level != levels[0] || summary.Jobs[3].Level != levels[3] {
+		t.Errorf("levels = %v, %v", summary.Jobs[0].Level, summary.Jobs[3].Level)
+	}
+}
+
+func TestRunParallelism(t *testing.T) {
+	var jobs []Job
+	for _, name := range []string{"a", "b", "c", "d"} {
+		jobs = append(jobs, Job{Name: name, Command: sh("sleep 0.2")})
+	}
+
+	start := time.Now()
+	if _, err := Run(context.Background(), jobs, Options{Parallelism: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// two batches of two
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("took %v", elapsed)
+	}
+}
+
+func TestRunFailFast(t *testing.T) {
+	jobs := []Job{
+		{Name: "fail", Command: sh("exit 3")},
+		{Name: "slow", Command: sh("sleep 5")},
+		{Name: "after-fail", Command: sh("true"), DependsOn: []string{"fail"}},
+		{Name: "after-after", Command: sh("true"), DependsOn: []string{"after-fail"}},
+		{Name: "after-slow", Command: sh("true"), DependsOn: []string{"slow"}},
+	}
+
+	start := time.Now()
+	summary, err := Run(context.Background(), jobs, Options{Parallelism: 2, FailFast: true})
+	if err == nil || !strings.Contains(err.Error(), "fail") {
+		t.Errorf("err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("slow job not canceled, took %v", elapsed)
+	}
+
+	want := map[string]Status{
+		"fail":        Failed,
+		"slow":        Canceled,
+		"after-fail":  Skipped,
+		"after-after": Skipped,
+		"after-slow":  Skipped,
+	}
+	got := statuses(summary)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%v: status = %v, want %v", name, got[name], status)
+		}
+	}
+	if code := summary.Jobs[0].Result.ExitCode; code != 3 {
+		t.Errorf("exit code = %v", code)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	// only the dependents of the failed job are skipped
+	summary, err := Run(context.Background(), []Job{
+		{Name: "fail", Command: sh("exit 1")},
+		{Name: "slow", Command: sh("sleep 0.2")},
+		{Name: "after-fail", Command: sh("true"), DependsOn: []string{"fail", "slow"}},
+		{Name: "after-slow", Command: sh("true"), DependsOn: []string{"slow"}},
+	}, Options{Parallelism: 2})
+	if err == nil {
+		t.Error("expected an error")
+	}
+
+	want := map[string]Status{"fail": Failed, "slow": Succeeded, "after-fail": Skipped, "after-slow": Succeeded}
+	got := statuses(summary)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%v: status = %v, want %v", name, got[name], status)
+		}
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	tests := map[string]struct {
+		jobs []Job
+		err  string
+	}{
+		"duplicate": {
+			jobs: []Job{{Name: "a"}, {Name: "a"}},
+			err:  `duplicate job "a"`,
+		},
+		"unknown": {
+			jobs: []Job{{Name: "a", DependsOn: []string{"b"}}},
+			err:  `"a" depends on unknown job "b"`,
+		},
+		"cycle": {
+			jobs: []Job{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			err: "cycle a -> c -> b -> a",
+		},
+		"self": {
+			jobs: []Job{{Name: "a", DependsOn: []string{"a"}}},
+			err:  "cycle a -> a",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			summary, err := Run(context.Background(), test.jobs, Options{})
+			if summary != nil || err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("err = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	var output bytes.Buffer
+	summary, err := Run(context.Background(), []Job{
+		{Name: "touch", Command: sh("touch should-not-exist")},
+		{Name: "after", Command: sh("true"), DependsOn: []string{"touch"}},
+	}, Options{DryRun: true, Output: &output})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range summary.Jobs {
+		if job.Status != Planned || job.Result != nil {
+			t.Errorf("%v: status = %v", job.Name, job.Status)
+		}
+	}
+	if output.Len() > 0 {
+		t.Errorf("output = %q", output.String())
+	}
+
+	var table bytes.Buffer
+	summary.Print(&table)
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "1") || !strings.Contains(lines[2], "after") || lines[3] != "2 jobs: 2 planned in 0s" {
+		t.Errorf("table:\n%v", table.String())
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	var output bytes.Buffer
+	_, err := Run(context.Background(), []Job{
+		{Name: "a", Command: sh("echo one; echo two")},
+		{Name: "b", Command: sh("echo three"), DependsOn: []string{"a"}},
+	}, Options{Output: &output})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[a] one\n[a] two\n[b] three\n"; output.String() != want {
+		t.Errorf("output = %q, want %q", output.String(), want)
+	}
+}
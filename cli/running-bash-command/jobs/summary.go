@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type Summary struct {
+	Jobs     []JobResult // in the order they were given
+	Duration time.Duration
+}
+
+func (s *Summary) failed() []string {
+	var names []string
+	for _, job := range s.Jobs {
+		if job.Status == Failed {
+			names = append(names, job.Name)
+		}
+	}
+	return names
+}
+
+// Print writes a table of the jobs, sorted by level, and the totals
+func (s *Summary) Print(w io.Writer) {
+	jobs := append([]JobResult{}, s.Jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].Level < jobs[j].Level })
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LEVEL\tJOB\tSTATUS\tEXIT\tDURATION\tDEPENDS ON\tCOMMAND")
+	counts := map[Status]int{}
+	for _, job := range jobs {
+		counts[job.Status]++
+
+		exit, duration := "-", "-"
+		if job.Result != nil {
+			exit = fmt.Sprint(job.Result.ExitCode)
+			if job.Result.Signal != nil {
+				exit = job.Result.Signal.String()
+			}
+		}
+		if job.Duration > 0 {
+			duration = job.Duration.Round(time.Millisecond).String()
+		}
+		dependsOn := strings.Join(job.DependsOn, ", ")
+		if dependsOn == "" {
+			dependsOn = "-"
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", job.Level, job.Name, job.Status, exit, duration, dependsOn, job.Command)
+	}
+	table.Flush()
+
+	var totals []string
+	for _, status := range []Status{Planned, Succeeded, Failed, Skipped, Canceled} {
+		if counts[status] > 0 {
+			totals = append(totals, fmt.Sprintf("%v %v", counts[status], status))
+		}
+	}
+	fmt.Fprintf(w, "%v jobs: %v in %v\n", len(s.Jobs), strings.Join(totals, ", "), s.Duration.Round(time.Millisecond))
+}
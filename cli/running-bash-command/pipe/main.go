@@ -1,41 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
+	"running-bash-command/runner"
+	"time"
 )
 
 func main() {
-	cmd1 := exec.Command("curl", "https://jsonplaceholder.typicode.com/todos/11")
-	cmd2 := exec.Command("grep", "title")
+	p := runner.NewPipeline().
+		Command("curl", "-s", "https://jsonplaceholder.typicode.com/todos/11").
+		Command("grep", "title")
+	p.Timeout = 30 * time.Second
 
-	// Create the pipe
-	pipe, err := cmd1.StdoutPipe()
-	if err != nil {
-		log.Fatal(err)
-	}
-	cmd2.Stdin = pipe
-
-	// Start the second command first
-	cmd2.Stdout = os.Stdout // Or capture output differently
-	if err := cmd2.Start(); err != nil {
-		log.Fatal(err)
-	}
+	result, err := p.Run(context.Background())
 
-	// Then run the first command
-	if err := cmd1.Run(); err != nil {
+	var exitErr *runner.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
 		log.Fatal(err)
 	}
 
-	// Wait for the second command to complete
-	if err := cmd2.Wait(); err != nil {
-		// grep returns error when no matches found, which is normal
-		if exiterr, ok := err.(*exec.ExitError); ok {
-			fmt.Printf("grep exited with status %d\n", exiterr.ExitCode())
-		} else {
-			log.Fatal(err)
-		}
+	fmt.Print(string(result.Stdout))
+	for _, stage := range result.Stages {
+		// grep exits with 1 when no line matches, which is normal
+		fmt.Println(stage)
 	}
 }
@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os/exec"
+	"os"
+	"running-bash-command/runner"
+	"time"
 )
 
 func main() {
-	cmd := exec.Command("ls", "-l")
-	output, err := cmd.Output()
-
+	// the output is printed as it is read, not once the command is done
+	result, err := runner.Run(context.Background(), runner.Command{
+		Name:    "ls",
+		Args:    []string{"-l"},
+		Timeout: 10 * time.Second,
+		Tee:     os.Stdout,
+		Prefix:  "[ls] ",
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Println(string(output))
+	fmt.Printf("exit code %v in %v\n", result.ExitCode, result.Duration)
 }
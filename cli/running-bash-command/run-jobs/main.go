@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"running-bash-command/jobs"
+	"running-bash-command/runner"
+)
+
+func sh(script string) runner.Command {
+	return runner.Command{Name: "sh", Args: []string{"-c", script}}
+}
+
+func main() {
+	var opts jobs.Options
+	flag.IntVar(&opts.Parallelism, "j", 0, "number of jobs running at once, the number of CPUs if 0")
+	flag.BoolVar(&opts.FailFast, "fail-fast", false, "cancel every job on the first failure")
+	flag.BoolVar(&opts.DryRun, "n", false, "print the plan without running anything")
+	flag.Parse()
+	opts.Output = os.Stdout
+
+	summary, err := jobs.Run(context.Background(), []jobs.Job{
+		{Name: "fetch", Command: sh("echo fetching; sleep 0.2")},
+		{Name: "lint", Command: sh("echo linting; sleep 0.1")},
+		{Name: "build", Command: sh("echo building; sleep 0.3"), DependsOn: []string{"fetch"}},
+		{Name: "test", Command: sh("echo testing; echo 1 test failed >&2; exit 1"), DependsOn: []string{"build"}},
+		{Name: "package", Command: sh("echo packaging"), DependsOn: []string{"build", "lint"}},
+		{Name: "deploy", Command: sh("echo deploying"), DependsOn: []string{"test", "package"}},
+	}, opts)
+	if summary == nil {
+		// the graph is invalid
+		log.Fatal(err)
+	}
+
+	summary.Print(os.Stdout)
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"bytes"
+	"sync"
+)
+
+// capture keeps the first max bytes written to it (all if max < 0) and
+// discards the rest, so the process never blocks on a full pipe
+type capture struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func newCapture(max int) *capture {
+	return &capture{max: max}
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(p)
+	if c.max >= 0 && c.buf.Len()+len(p) > c.max {
+		p = p[:c.max-c.buf.Len()]
+		c.truncated = true
+	}
+	c.buf.Write(p)
+	return n, nil
+}
+
+func (c *capture) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return bytes.Clone(c.buf.Bytes())
+}
+
+func (c *capture) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.truncated
+}
+
+// truncate marks the capture as incomplete, eg: when the rest of the
+// output could not be read
+func (c *capture) truncate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.truncated = true
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// output connects stdout and stderr to their captures, and to the streamer,
+// through pipes that Run reads itself: exec would close its own pipes
+// WaitDelay after the process exits, even while a slow OnLine is still
+// reading lines the process wrote before exiting
+type output struct {
+	stdout *capture
+	stderr *capture
+	stream *streamer
+	pipes  []*outputPipe
+	wg     sync.WaitGroup
+}
+
+type outputPipe struct {
+	r, w    *os.File
+	dst     io.Writer
+	capture *capture
+	exited  atomic.Bool
+	delay   time.Duration
+}
+
+func (c Command) output() (*output, error) {
+	o := &output{stdout: newCapture(c.maxOutput())}
+	o.stderr = o.stdout
+	if !c.Combined {
+		o.stderr = newCapture(c.maxOutput())
+	}
+
+	var stdout, stderr io.Writer = o.stdout, o.stderr
+	if c.streaming() {
+		stream, err := newStreamer(c)
+		if err != nil {
+			return nil, err
+		}
+		o.stream = stream
+		// separate pipes even when combined, so lines keep their stream
+		stdout, stderr = stream.writer(false, o.stdout), stream.writer(true, o.stderr)
+	}
+
+	if err := o.pipe(stdout, o.stdout); err != nil {
+		o.close()
+		return nil, err
+	}
+	// without lines to tell apart, a single pipe keeps the exact order
+	if c.Combined && o.stream == nil {
+		return o, nil
+	}
+	if err := o.pipe(stderr, o.stderr); err != nil {
+		o.close()
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *output) pipe(dst io.Writer, capture *capture) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	o.pipes = append(o.pipes, &outputPipe{r: r, w: w, dst: dst, capture: capture})
+	return nil
+}
+
+// files returns the ends the process writes to
+func (o *output) files() (stdout, stderr *os.File) {
+	return o.pipes[0].w, o.pipes[len(o.pipes)-1].w
+}
+
+// start reads the pipes once the process has its own copies of the write ends
+func (o *output) start() {
+	for _, p := range o.pipes {
+		p.w.Close()
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			p.copy()
+		}()
+	}
+}
+
+// close releases the pipes of a process that did not start
+func (o *output) close() {
+	for _, p := range o.pipes {
+		p.r.Close()
+		p.w.Close()
+	}
+	if o.stream != nil {
+		o.stream.close()
+	}
+}
+
+// wait reads what is left in the pipes once the process exited. A pipe is
+// abandoned when nothing can be read from it for delay, because a child
+// still holds it open, however long the lines already read take to handle.
+func (o *output) wait(delay time.Duration) {
+	for _, p := range o.pipes {
+		p.exit(delay)
+	}
+	o.wg.Wait()
+	if o.stream != nil {
+		o.stream.close()
+	}
+}
+
+func (p *outputPipe) exit(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	p.delay = delay
+	p.exited.Store(true)
+	if err := p.r.SetReadDeadline(time.Now().Add(delay)); err != nil {
+		// pipes without deadlines are closed, slow consumer or not
+		time.AfterFunc(delay, func() { p.r.Close() })
+	}
+}
+
+func (p *outputPipe) copy() {
+	defer p.r.Close()
+
+	buf := make([]byte, 32<<10)
+	for {
+		// the deadline only covers the wait for data, not the time dst
+		// spends blocked on a slow consumer
+		if p.exited.Load() {
+			p.r.SetReadDeadline(time.Now().Add(p.delay))
+		}
+		n, err := p.r.Read(buf)
+		if n > 0 {
+			p.dst.Write(buf[:n])
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				// the rest of the output is lost
+				p.capture.truncate()
+			}
+			return
+		}
+	}
+}
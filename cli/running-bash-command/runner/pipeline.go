@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StageFunc is an in-process stage, it reads the output of the previous
+// stage from r and writes its own to w
+type StageFunc func(ctx context.Context, r io.Reader, w io.Writer) error
+
+type stage struct {
+	name    string
+	command *Command
+	fn      StageFunc
+}
+
+// Pipeline chains commands and functions like a shell pipeline, eg:
+//
+//	runner.NewPipeline().Command("seq", "100").Command("sort", "-r").Command("head", "-3").Run(ctx)
+//
+// Stages run concurrently, connected by os pipes, so large outputs stream
+// through without being buffered.
+type Pipeline struct {
+	stages []stage
+
+	Stdin   io.Reader
+	Dir     string
+	Env     []string
+	Timeout time.Duration
+	// KillGrace and MaxOutput are used as in Command, MaxOutput caps the
+	// output of the last stage and the stderr shared by the commands
+	KillGrace time.Duration
+	MaxOutput int
+
+	// LastStatusOnly makes the status of the pipeline the status of its last
+	// stage, like bash does without `set -o pipefail`. By default the status
+	// is the one of the last stage that failed.
+	LastStatusOnly bool
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Command appends a command stage
+func (p *Pipeline) Command(name string, args ...string) *Pipeline {
+	c := Command{Name: name, Args: args}
+	p.stages = append(p.stages, stage{name: c.String(), command: &c})
+	return p
+}
+
+// Func appends an in-process stage, name identifies it in the results
+func (p *Pipeline) Func(name string, fn StageFunc) *Pipeline {
+	p.stages = append(p.stages, stage{name: name, fn: fn})
+	return p
+}
+
+// String returns the pipeline as a shell command line
+func (p *Pipeline) String() string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.name
+	}
+	return strings.Join(names, " | ")
+}
+
+// StageResult describes how a stage of a pipeline ended
+type StageResult struct {
+	Name     string
+	ExitCode int // 127 when a command could not start, 1 when a function failed
+	Signal   os.Signal
+	Duration time.Duration
+	// Err is the error of a function stage, or why a command could not start
+	Err error
+}
+
+type PipelineResult struct {
+	// Result holds the status of the pipeline, the output of the last stage
+	// and the stderr of every command
+	Result
+	Stages []StageResult
+}
+
+// Run starts every stage and waits for all of them. The error is an
+// *ExitError when the status of the pipeline is not 0.
+func (p *Pipeline) Run(ctx context.Context) (*PipelineResult, error) {
+	if len(p.stages) == 0 {
+		return nil, errors.New("runner: empty pipeline")
+	}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	max := Command{MaxOutput: p.MaxOutput}.maxOutput()
+	stdout := newCapture(max)
+	stderr := newCapture(max)
+
+	// stage i reads from inputs[i] and writes to outputs[i], the pipes
+	// between stages are closed by the parent once their stages are done
+	inputs := make([]io.Reader, len(p.stages))
+	outputs := make([]io.Writer, len(p.stages))
+	inputs[0] = p.Stdin
+	outputs[len(p.stages)-1] = stdout
+	pipes := pipes{}
+	for i := 1; i < len(p.stages); i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			pipes.closeAll()
+			return nil, err
+		}
+		outputs[i-1], inputs[i] = w, r
+		pipes[r], pipes[w] = true, true
+	}
+
+	start := time.Now()
+	results := make([]StageResult, len(p.stages))
+	// set when a stage was stopped by ctx rather than ending on its own
+	var canceled atomic.Bool
+	var wg sync.WaitGroup
+	for i, s := range p.stages {
+		results[i].Name = s.name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stageStart := time.Now()
+			if s.fn != nil {
+				if p.runFunc(ctx, s.fn, pipes, inputs[i], outputs[i], &results[i]) {
+					canceled.Store(true)
+				}
+			} else if p.runCommand(ctx, *s.command, pipes, inputs[i], outputs[i], stderr, &results[i]) {
+				canceled.Store(true)
+			}
+			results[i].Duration = time.Since(stageStart)
+		}()
+	}
+	wg.Wait()
+
+	result := &PipelineResult{
+		Result: Result{
+			Duration:        time.Since(start),
+			TimedOut:        canceled.Load() && errors.Is(ctx.Err(), context.DeadlineExceeded),
+			Stdout:          stdout.Bytes(),
+			Stderr:          stderr.Bytes(),
+			StdoutTruncated: stdout.Truncated(),
+			StderrTruncated: stderr.Truncated(),
+		},
+		Stages: results,
+	}
+
+	status := results[len(results)-1]
+	if !p.LastStatusOnly {
+		for _, r := range results {
+			if r.ExitCode != 0 {
+				status = r
+			}
+		}
+	}
+	result.ExitCode, result.Signal = status.ExitCode, status.Signal
+
+	if result.ExitCode != 0 {
+		exitErr := &ExitError{Command: p.String(), Result: &result.Result}
+		if canceled.Load() {
+			exitErr.Err = ctx.Err()
+		}
+		return result, exitErr
+	}
+	return result, nil
+}
+
+// runCommand reports whether the command was cancelled
+func (p *Pipeline) runCommand(ctx context.Context, c Command, pipes pipes, in io.Reader, out io.Writer, stderr io.Writer, result *StageResult) bool {
+	c.Dir, c.Env, c.KillGrace = p.Dir, p.Env, p.KillGrace
+	cmd, cancel := c.command(ctx)
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+
+	err := cmd.Start()
+	// the child has its own copies of the pipes, closing ours lets the
+	// neighbours see EOF or EPIPE when it exits
+	pipes.close(in)
+	pipes.close(out)
+	if err != nil {
+		// like bash, a command that can't start fails with 127
+		result.ExitCode, result.Err = 127, err
+		return false
+	}
+
+	cmd.Wait()
+	result.ExitCode, result.Signal = exitStatus(cmd)
+	return cancel.stop()
+}
+
+// runFunc reports whether the function failed because ctx was done
+func (p *Pipeline) runFunc(ctx context.Context, fn StageFunc, pipes pipes, in io.Reader, out io.Writer, result *StageResult) bool {
+	if in == nil {
+		in = strings.NewReader("")
+	}
+
+	err := fn(ctx, in, out)
+	pipes.close(out)
+	// an upstream command still writing gets EPIPE instead of blocking
+	pipes.close(in)
+
+	if err != nil {
+		result.ExitCode, result.Err = 1, err
+	}
+	return err != nil && ctx.Err() != nil
+}
+
+// pipes holds the ends of the pipes created by Run, other readers and
+// writers (eg: Stdin) belong to the caller and are never closed
+type pipes map[*os.File]bool
+
+func (p pipes) close(v interface{}) {
+	if f, ok := v.(*os.File); ok && p[f] {
+		f.Close()
+	}
+}
+
+func (p pipes) closeAll() {
+	for f := range p {
+		f.Close()
+	}
+}
+
+func (r StageResult) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%v: %v", r.Name, r.Err)
+	case r.Signal != nil:
+		return fmt.Sprintf("%v: killed by %v", r.Name, r.Signal)
+	}
+	return fmt.Sprintf("%v: exit status %v", r.Name, r.ExitCode)
+}
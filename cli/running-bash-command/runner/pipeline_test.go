@@ -0,0 +1,130 @@
+//go:build unix
+
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	result, err := NewPipeline().
+		Command("seq", "10").
+		Command("sort", "-rn").
+		Command("head", "-3").
+		Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "10\n9\n8\n" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+	if len(result.Stages) != 3 || result.Stages[1].Name != "sort -rn" {
+		t.Errorf("stages = %v", result.Stages)
+	}
+}
+
+func TestPipelineLargeOutput(t *testing.T) {
+	// far more than a pipe buffer goes through every stage
+	result, err := NewPipeline().
+		Command("seq", "1000000").
+		Command("sort", "-n").
+		Command("wc", "-l").
+		Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(result.Stdout)) != "1000000" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+}
+
+func TestPipelineFunc(t *testing.T) {
+	double := func(ctx context.Context, r io.Reader, w io.Writer) error {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var n int
+			fmt.Sscan(scanner.Text(), &n)
+			fmt.Fprintln(w, n*2)
+		}
+		return scanner.Err()
+	}
+
+	p := NewPipeline().Func("double", double).Command("sort", "-n").Func("double", double).Command("wc", "-l")
+	p.Stdin = strings.NewReader("3\n1\n2\n")
+	result, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(result.Stdout)) != "3" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+
+	result, err = NewPipeline().Func("seq", func(ctx context.Context, r io.Reader, w io.Writer) error {
+		fmt.Fprintln(w, "2\n1")
+		return nil
+	}).Command("sort").Run(context.Background())
+	if err != nil || string(result.Stdout) != "1\n2\n" {
+		t.Errorf("stdout = %q, error %v", result.Stdout, err)
+	}
+}
+
+func TestPipelineStatus(t *testing.T) {
+	failing := func() *Pipeline {
+		return NewPipeline().Command("sh", "-c", "echo a; exit 3").Command("cat").Command("sh", "-c", "cat; exit 0")
+	}
+
+	// pipefail: the last failing stage decides
+	result, err := failing().Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || result.ExitCode != 3 {
+		t.Errorf("exit code %v, error %v", result.ExitCode, err)
+	}
+	codes := []int{result.Stages[0].ExitCode, result.Stages[1].ExitCode, result.Stages[2].ExitCode}
+	if codes[0] != 3 || codes[1] != 0 || codes[2] != 0 {
+		t.Errorf("stage exit codes = %v", codes)
+	}
+
+	p := failing()
+	p.LastStatusOnly = true
+	if result, err := p.Run(context.Background()); err != nil || result.ExitCode != 0 {
+		t.Errorf("exit code %v, error %v", result.ExitCode, err)
+	}
+}
+
+func TestPipelineFailures(t *testing.T) {
+	result, err := NewPipeline().
+		Command("no-such-command-exists").
+		Func("fail", func(ctx context.Context, r io.Reader, w io.Writer) error {
+			io.Copy(io.Discard, r)
+			return errors.New("boom")
+		}).
+		Command("cat").
+		Run(context.Background())
+	if err == nil || result.ExitCode != 1 {
+		t.Fatalf("exit code %v, error %v", result.ExitCode, err)
+	}
+	if result.Stages[0].ExitCode != 127 || result.Stages[0].Err == nil || result.Stages[1].Err == nil {
+		t.Errorf("stages = %v", result.Stages)
+	}
+}
+
+func TestPipelineTimeout(t *testing.T) {
+	p := NewPipeline().Command("sleep", "30").Command("cat")
+	p.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	result, err := p.Run(context.Background())
+
+	if !errors.Is(err, context.DeadlineExceeded) || !result.TimedOut {
+		t.Errorf("result %+v, error %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %v", elapsed)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup only kills the process itself, process groups are unix only
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration, cancel *cancellation) {
+	cmd.Cancel = func() error {
+		cancel.start(0, nil)
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = grace
+}
+
+func exitSignal(state *os.ProcessState) os.Signal {
+	return nil
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in its own process group, so
+// cancelling it also stops its children: SIGTERM first, then SIGKILL after
+// grace if the group is still alive
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration, cancel *cancellation) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+			return err
+		}
+		cancel.start(grace, func() { syscall.Kill(-pgid, syscall.SIGKILL) })
+		return nil
+	}
+	// children that keep the output pipes open don't block Wait forever
+	cmd.WaitDelay = grace + time.Second
+}
+
+func exitSignal(state *os.ProcessState) os.Signal {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal()
+	}
+	return nil
+}
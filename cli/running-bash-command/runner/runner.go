@@ -0,0 +1,237 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxOutput is the number of bytes captured per stream when
+	// Command.MaxOutput is 0
+	DefaultMaxOutput = 1 << 20
+	// DefaultKillGrace is how long a cancelled process group gets between
+	// SIGTERM and SIGKILL when Command.KillGrace is 0
+	DefaultKillGrace = 5 * time.Second
+)
+
+// Command describes a process to run, the zero values are sensible defaults
+type Command struct {
+	Name  string
+	Args  []string
+	Dir   string
+	Env   []string // nil inherits the environment of the current process
+	Stdin io.Reader
+
+	// Timeout kills the process group once elapsed, 0 means no timeout
+	Timeout time.Duration
+	// KillGrace is the delay between SIGTERM and SIGKILL on cancellation
+	KillGrace time.Duration
+
+	// Combined captures stdout and stderr interleaved in Result.Stdout, in
+	// the exact order they were written unless the output is streamed: lines
+	// are then read from separate pipes to keep Line.Stderr, and lines
+	// written to both streams at the same instant may be swapped
+	Combined bool
+	// MaxOutput caps the bytes kept per stream, the rest is read and
+	// discarded. Negative means unlimited.
+	MaxOutput int
+
+	// OnLine is called with every line of output as soon as it is read, one
+	// line at a time in the order they were read, stdout and stderr lines
+	// interleaved. A slow OnLine pauses the process once a few lines are
+	// queued, the output is never buffered without bound, and every line is
+	// delivered even if the process exits long before OnLine is done.
+	OnLine func(Line)
+	// Tee and TeeFile (created or truncated) receive every line as it is
+	// read, preceded by Prefix (eg: "[build] " for parallel commands)
+	Tee     io.Writer
+	TeeFile string
+	Prefix  string
+}
+
+// Result describes a finished process
+type Result struct {
+	ExitCode int // -1 when the process was killed by a signal
+	// Signal is the signal that killed the process, nil if it exited
+	Signal   os.Signal
+	Duration time.Duration
+	TimedOut bool
+
+	Stdout []byte
+	Stderr []byte // empty when the output is combined
+
+	// StdoutTruncated and StderrTruncated are set when the output exceeded
+	// MaxOutput, or when a child still held the stream open, with nothing to
+	// read, for KillGrace after the process exited and the rest was not read
+	StdoutTruncated bool
+	StderrTruncated bool
+}
+
+func (r *Result) Success() bool {
+	return r.ExitCode == 0
+}
+
+// ExitError is returned when the process exited with a non zero status or
+// was killed. It wraps the context error when the process was cancelled.
+type ExitError struct {
+	Command string
+	Result  *Result
+	Err     error
+}
+
+func (e *ExitError) Error() string {
+	var msg string
+	switch {
+	case e.Result.TimedOut:
+		msg = fmt.Sprintf("%v timed out after %v", e.Command, e.Result.Duration.Round(time.Millisecond))
+	case e.Result.Signal != nil:
+		msg = fmt.Sprintf("%v was killed by %v", e.Command, e.Result.Signal)
+	default:
+		msg = fmt.Sprintf("%v exited with status %v", e.Command, e.Result.ExitCode)
+	}
+
+	// the last line of stderr usually explains the failure
+	if stderr := strings.TrimSpace(string(e.Result.Stderr)); stderr != "" {
+		msg += ": " + stderr[strings.LastIndex(stderr, "\n")+1:]
+	}
+	return msg
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Run starts the command and waits for it. The whole process group is
+// killed when ctx is done or the timeout elapses. The error is an
+// *ExitError if the process did not exit with status 0, the result is nil
+// only if the process could not start.
+func Run(ctx context.Context, c Command) (*Result, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd, cancel := c.command(ctx)
+	out, err := c.output()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout, cmd.Stderr = out.files()
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		out.close()
+		return nil, err
+	}
+	out.start()
+	err = cmd.Wait()
+	canceled := cancel.stop()
+	out.wait(cmd.WaitDelay)
+
+	result := &Result{
+		ExitCode:        -1,
+		Duration:        time.Since(start),
+		Stdout:          out.stdout.Bytes(),
+		StdoutTruncated: out.stdout.Truncated(),
+	}
+	if !c.Combined {
+		result.Stderr = out.stderr.Bytes()
+		result.StderrTruncated = out.stderr.Truncated()
+	}
+	result.ExitCode, result.Signal = exitStatus(cmd)
+	// the deadline may pass after the process exited on its own
+	result.TimedOut = canceled && errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return result, nil
+	case canceled:
+		return result, &ExitError{Command: c.String(), Result: result, Err: ctx.Err()}
+	case errors.Is(err, exec.ErrWaitDelay) && result.ExitCode == 0:
+		// only the copy of Stdin was cut short, the process succeeded
+		return result, nil
+	case errors.As(err, &exitErr), errors.Is(err, exec.ErrWaitDelay):
+		return result, &ExitError{Command: c.String(), Result: result}
+	}
+	return result, err
+}
+
+// exitStatus returns the exit code and signal of a waited command, -1 and
+// nil if it did not run
+func exitStatus(cmd *exec.Cmd) (int, os.Signal) {
+	if cmd.ProcessState == nil {
+		return -1, nil
+	}
+	return cmd.ProcessState.ExitCode(), exitSignal(cmd.ProcessState)
+}
+
+// command builds the exec.Cmd, cancelling ctx terminates its process group
+func (c Command) command(ctx context.Context) (*exec.Cmd, *cancellation) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Env
+	cmd.Stdin = c.Stdin
+
+	grace := c.KillGrace
+	if grace == 0 {
+		grace = DefaultKillGrace
+	}
+	cancel := &cancellation{}
+	setProcessGroup(cmd, grace, cancel)
+	return cmd, cancel
+}
+
+// cancellation records whether a command was cancelled and holds the timer
+// of the SIGKILL that follows, it must be stopped once the command is
+// waited: the process group id can be reused by then
+type cancellation struct {
+	mu       sync.Mutex
+	canceled bool
+	stopped  bool
+	kill     *time.Timer
+}
+
+// start records the cancellation and runs kill after grace, unless the
+// command was waited in between
+func (c *cancellation) start(grace time.Duration, kill func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.canceled = true
+	if kill != nil && !c.stopped {
+		c.kill = time.AfterFunc(grace, kill)
+	}
+}
+
+// stop cancels the pending kill and reports whether the command was cancelled
+func (c *cancellation) stop() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	if c.kill != nil {
+		c.kill.Stop()
+	}
+	return c.canceled
+}
+
+func (c Command) maxOutput() int {
+	if c.MaxOutput == 0 {
+		return DefaultMaxOutput
+	}
+	return c.MaxOutput
+}
+
+// String returns the command line, eg: ls -l
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
@@ -0,0 +1,139 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunCapturesStreams(t *testing.T) {
+	result, err := Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "out\n" || string(result.Stderr) != "err\n" {
+		t.Errorf("stdout %q, stderr %q", result.Stdout, result.Stderr)
+	}
+}
+
+func TestRunCombined(t *testing.T) {
+	result, err := Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}, Combined: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "out\nerr\n" || result.Stderr != nil {
+		t.Errorf("stdout %q, stderr %q", result.Stdout, result.Stderr)
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	result, err := Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo failed >&2; exit 3"}})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || result.ExitCode != 3 || result.Signal != nil {
+		t.Fatalf("result %+v, error %v", result, err)
+	}
+	if !strings.HasSuffix(err.Error(), "exited with status 3: failed") {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestRunTruncates(t *testing.T) {
+	result, err := Run(context.Background(), Command{Name: "seq", Args: []string{"100000"}, MaxOutput: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "1\n2\n3\n4\n5\n" || !result.StdoutTruncated || result.StderrTruncated {
+		t.Errorf("stdout %q, truncated %v", result.Stdout, result.StdoutTruncated)
+	}
+}
+
+func TestRunTimeoutKillsProcessGroup(t *testing.T) {
+	start := time.Now()
+	// the background sleep keeps stdout open, it must be killed too
+	result, err := Run(context.Background(), Command{
+		Name:      "sh",
+		Args:      []string{"-c", "sleep 30 & sleep 30"},
+		Timeout:   100 * time.Millisecond,
+		KillGrace: time.Second,
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) || !result.TimedOut {
+		t.Fatalf("result %+v, error %v", result, err)
+	}
+	if result.Signal != syscall.SIGTERM || result.ExitCode != -1 {
+		t.Errorf("signal %v, exit code %v", result.Signal, result.ExitCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %v, the group was not killed", elapsed)
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	result, err := Run(context.Background(), Command{Name: "no-such-command-exists"})
+	if result != nil || !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("result %+v, error %v", result, err)
+	}
+}
+
+func TestRunKillsAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	result, err := Run(ctx, Command{
+		Name:      "sh",
+		Args:      []string{"-c", "trap '' TERM; sleep 30"},
+		KillGrace: 200 * time.Millisecond,
+	})
+	if !errors.Is(err, context.Canceled) || result.TimedOut {
+		t.Fatalf("result %+v, error %v", result, err)
+	}
+	if result.Signal != syscall.SIGKILL {
+		t.Errorf("signal = %v, want SIGKILL", result.Signal)
+	}
+}
+
+func TestRunNotTimedOutWhenExited(t *testing.T) {
+	// the process exits at once, the deadline passes while its child still
+	// holds stdout: nothing was killed
+	result, err := Run(context.Background(), Command{
+		Name:      "sh",
+		Args:      []string{"-c", "sleep 0.3 & exit 0"},
+		Timeout:   100 * time.Millisecond,
+		KillGrace: time.Second,
+	})
+	if err != nil || result.TimedOut {
+		t.Fatalf("result %+v, error %v", result, err)
+	}
+}
+
+func TestCancellationStopsKill(t *testing.T) {
+	killed := make(chan struct{}, 1)
+	c := &cancellation{}
+	c.start(50*time.Millisecond, func() { killed <- struct{}{} })
+	if !c.stop() {
+		t.Error("stop() = false after start")
+	}
+
+	select {
+	case <-killed:
+		t.Error("kill ran after the command was waited")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// a cancellation after the wait does not schedule a kill either
+	c = &cancellation{}
+	c.stop()
+	c.start(0, func() { killed <- struct{}{} })
+	select {
+	case <-killed:
+		t.Error("kill ran after the command was waited")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// lineBuffer is the number of lines queued for a slow OnLine or Tee
+	// before reading pauses
+	lineBuffer = 64
+	// maxLineLength splits lines without a newline in sight
+	maxLineLength = 64 << 10
+)
+
+// Line is a line of output, without its line ending
+type Line struct {
+	Text   string
+	Time   time.Time // when the line was read
+	Stderr bool
+}
+
+// streamer sends the lines of both streams to a single goroutine, which
+// calls OnLine and writes to the tees in the order the lines were read
+type streamer struct {
+	// mu makes the writers handle one read at a time, the lines and the
+	// combined capture get the reads in the same order
+	mu      sync.Mutex
+	lines   chan Line
+	done    chan struct{}
+	onLine  func(Line)
+	tees    []io.Writer
+	prefix  string
+	file    *os.File
+	writers []*lineWriter
+}
+
+func (c Command) streaming() bool {
+	return c.OnLine != nil || c.Tee != nil || c.TeeFile != ""
+}
+
+func newStreamer(c Command) (*streamer, error) {
+	s := &streamer{
+		lines:  make(chan Line, lineBuffer),
+		done:   make(chan struct{}),
+		onLine: c.OnLine,
+		prefix: c.Prefix,
+	}
+	if c.Tee != nil {
+		s.tees = append(s.tees, c.Tee)
+	}
+	if c.TeeFile != "" {
+		file, err := os.Create(c.TeeFile)
+		if err != nil {
+			return nil, err
+		}
+		s.file = file
+		s.tees = append(s.tees, file)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+func (s *streamer) run() {
+	defer close(s.done)
+	for line := range s.lines {
+		if s.onLine != nil {
+			s.onLine(line)
+		}
+		// a single write per line keeps lines whole when parallel commands
+		// tee to the same writer
+		for _, tee := range s.tees {
+			tee.Write([]byte(s.prefix + line.Text + "\n"))
+		}
+	}
+}
+
+// writer returns the writer of a stream, it also writes to capture
+func (s *streamer) writer(stderr bool, capture io.Writer) *lineWriter {
+	w := &lineWriter{mu: &s.mu, streamer: s, stderr: stderr, capture: capture}
+	s.writers = append(s.writers, w)
+	return w
+}
+
+// close sends the last incomplete lines and waits until every line is handled
+func (s *streamer) close() {
+	for _, w := range s.writers {
+		w.flush()
+	}
+	close(s.lines)
+	<-s.done
+
+	if s.file != nil {
+		s.file.Close()
+	}
+}
+
+// lineWriter splits a stream in lines. Write blocks while the line queue is
+// full, the copy from the pipe then stops and the process blocks on its
+// writes: slow consumers get backpressure instead of unbounded buffering.
+type lineWriter struct {
+	mu       *sync.Mutex
+	streamer *streamer
+	stderr   bool
+	capture  io.Writer
+	partial  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.capture.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			if len(w.partial) >= maxLineLength {
+				w.send(w.partial)
+				w.partial = nil
+			}
+			break
+		}
+		w.send(bytes.TrimSuffix(w.partial[:i], []byte("\r")))
+		w.partial = w.partial[i+1:]
+	}
+
+	// don't keep the consumed lines alive
+	w.partial = append([]byte(nil), w.partial...)
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.send(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *lineWriter) send(text []byte) {
+	w.streamer.lines <- Line{Text: string(text), Time: time.Now(), Stderr: w.stderr}
+}
@@ -0,0 +1,163 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamLines(t *testing.T) {
+	var lines []Line
+	result, err := Run(context.Background(), Command{
+		Name: "sh",
+		// stdout and stderr are separate pipes, lines written to both at the
+		// same instant could be read in any order
+		Args:     []string{"-c", "echo one; sleep 0.05; echo two >&2; sleep 0.05; printf three"},
+		Combined: true,
+		OnLine:   func(line Line) { lines = append(lines, line) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	for i, line := range lines {
+		texts = append(texts, line.Text)
+		if line.Stderr != (line.Text == "two") {
+			t.Errorf("line %q: stderr = %v", line.Text, line.Stderr)
+		}
+		if i > 0 && line.Time.Before(lines[i-1].Time) {
+			t.Errorf("line %v was read before the previous one", i)
+		}
+	}
+	if strings.Join(texts, ",") != "one,two,three" {
+		t.Errorf("lines = %q", texts)
+	}
+	// the output is still captured
+	if string(result.Stdout) != "one\ntwo\nthree" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+}
+
+func TestStreamSeparatesStderr(t *testing.T) {
+	var stderr []string
+	_, err := Run(context.Background(), Command{
+		Name: "sh",
+		Args: []string{"-c", "echo out; echo err >&2"},
+		OnLine: func(line Line) {
+			if line.Stderr {
+				stderr = append(stderr, line.Text)
+			}
+		},
+	})
+	if err != nil || len(stderr) != 1 || stderr[0] != "err" {
+		t.Errorf("stderr lines = %q, error %v", stderr, err)
+	}
+}
+
+func TestStreamTee(t *testing.T) {
+	var tee bytes.Buffer
+	file := filepath.Join(t.TempDir(), "build.log")
+	_, err := Run(context.Background(), Command{
+		Name:    "seq",
+		Args:    []string{"3"},
+		Tee:     &tee,
+		TeeFile: file,
+		Prefix:  "[seq] ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[seq] 1\n[seq] 2\n[seq] 3\n"
+	if tee.String() != want {
+		t.Errorf("tee = %q, want %q", tee.String(), want)
+	}
+	if content, _ := os.ReadFile(file); string(content) != want {
+		t.Errorf("tee file = %q, want %q", content, want)
+	}
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "done")
+	release := make(chan struct{})
+	count := 0
+
+	done := make(chan error)
+	go func() {
+		_, err := Run(context.Background(), Command{
+			Name: "sh",
+			// far more than the line queue and a pipe buffer
+			Args:      []string{"-c", "seq 200000; touch " + marker},
+			MaxOutput: 16,
+			OnLine: func(line Line) {
+				if count == 0 {
+					<-release
+				}
+				count++
+			},
+		})
+		done <- err
+	}()
+
+	// the process must be blocked on its writes while OnLine is busy
+	time.Sleep(300 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the process finished while its output was not consumed")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if count != 200000 {
+		t.Errorf("got %v lines", count)
+	}
+}
+
+func TestStreamSlowConsumer(t *testing.T) {
+	// the process exits long before OnLine is done, no line is lost
+	count := 0
+	result, err := Run(context.Background(), Command{
+		Name:      "seq",
+		Args:      []string{"2000"},
+		KillGrace: 100 * time.Millisecond,
+		OnLine: func(line Line) {
+			time.Sleep(time.Millisecond)
+			count++
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2000 || result.StdoutTruncated {
+		t.Errorf("got %v lines, truncated %v", count, result.StdoutTruncated)
+	}
+}
+
+func TestStreamAbandonsHeldPipe(t *testing.T) {
+	// the background sleep keeps stdout open after sh exited
+	var lines []string
+	start := time.Now()
+	result, err := Run(context.Background(), Command{
+		Name:      "sh",
+		Args:      []string{"-c", "echo hi; sleep 3 &"},
+		KillGrace: 100 * time.Millisecond,
+		OnLine:    func(line Line) { lines = append(lines, line.Text) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %v", elapsed)
+	}
+	if len(lines) != 1 || lines[0] != "hi" || !result.StdoutTruncated {
+		t.Errorf("lines %q, truncated %v", lines, result.StdoutTruncated)
+	}
+}
@@ -2,21 +2,71 @@ package handlers
 
 import (
 	"clean-rest-api/model"
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/repository"
+	"clean-rest-api/types"
+	"errors"
+	"net/http"
 )
 
 type MessageHandler struct {
-	messages []model.Message
+	repo repository.MessageRepository
 }
 
-func NewMessageHandler() *MessageHandler {
-	return &MessageHandler{
-		messages: []model.Message{
-			{Id: 1, Title: "demo", Body: "demo message"},
-			{Id: 2, Title: "random", Body: "random message"},
-		},
+func NewMessageHandler(repo repository.MessageRepository) *MessageHandler {
+	return &MessageHandler{repo: repo}
+}
+
+type MessageInput struct {
+	Title string `json:"title" validate:"required,max=200"`
+	Body  string `json:"body" validate:"max=10000"`
+}
+
+type MessageIDRequest struct {
+	Id int `json:"-" path:"id" doc:"id of the message"`
+}
+
+type UpdateMessageRequest struct {
+	Id int `json:"-" path:"id" doc:"id of the message"`
+	MessageInput
+}
+
+func (m *MessageHandler) GetAllMessages(ctx *types.Context, _ struct{}) ([]model.Message, error) {
+	return m.repo.GetAll(ctx.Request.Context())
+}
+
+func (m *MessageHandler) GetMessage(ctx *types.Context, req MessageIDRequest) (*model.Message, error) {
+	message, err := m.repo.GetByID(ctx.Request.Context(), req.Id)
+	if err != nil {
+		return nil, repositoryError(err)
 	}
+	return message, nil
 }
 
-func (m *MessageHandler) GetAllMessages() []model.Message {
-	return m.messages
+func (m *MessageHandler) CreateMessage(ctx *types.Context, req MessageInput) (*model.Message, error) {
+	message := &model.Message{Title: req.Title, Body: req.Body}
+	if err := m.repo.Create(ctx.Request.Context(), message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (m *MessageHandler) UpdateMessage(ctx *types.Context, req UpdateMessageRequest) (*model.Message, error) {
+	message := &model.Message{Title: req.Title, Body: req.Body}
+	if err := m.repo.Update(ctx.Request.Context(), req.Id, message); err != nil {
+		return nil, repositoryError(err)
+	}
+	return message, nil
+}
+
+func (m *MessageHandler) DeleteMessage(ctx *types.Context, req MessageIDRequest) (cleanapi.NoContent, error) {
+	return cleanapi.NoContent{}, repositoryError(m.repo.Delete(ctx.Request.Context(), req.Id))
+}
+
+// repositoryError maps repository errors to http errors, others stay 500s
+func repositoryError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return &types.HttpError{Code: http.StatusNotFound, Message: err.Error(), Err: err}
+	}
+	return err
 }
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"clean-rest-api/repository"
+	"clean-rest-api/types"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRepositoryError(t *testing.T) {
+	err := repositoryError(repository.ErrNotFound)
+	if status := types.ErrorStatus(err); status != http.StatusNotFound {
+		t.Errorf("status = %v", status)
+	}
+	// the cause is kept for the logs and for errors.Is
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("error %v does not wrap ErrNotFound", err)
+	}
+
+	other := errors.New("disk full")
+	if err := repositoryError(other); err != other {
+		t.Errorf("other errors are returned as is, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package cleanapi
+
+import (
+	"clean-rest-api/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bind fills req from the json body, the path variables (`path:"id"`) and
+// the query string (`query:"name"`). Malformed input is a 400.
+func bind(ctx *types.Context, req interface{}) error {
+	v := reflect.ValueOf(req).Elem()
+
+	if hasBody(ctx.Request) && hasBodyFields(v.Type()) {
+		if err := json.NewDecoder(ctx.Request.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+			return types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("invalid json body: %v", err))
+		}
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	query := ctx.Request.URL.Query()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if name, ok := paramName(field, "path"); ok {
+			raw := ctx.Param(name)
+			if raw == "" {
+				continue
+			}
+			if err := setValue(v.Field(i), []string{raw}); err != nil {
+				return types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("invalid path parameter %v: %v", name, err))
+			}
+		}
+
+		if name, ok := paramName(field, "query"); ok {
+			raw, ok := query[name]
+			if !ok {
+				if strings.Contains(field.Tag.Get("query"), ",required") {
+					return types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("missing query parameter %v", name))
+				}
+				continue
+			}
+			if err := setValue(v.Field(i), raw); err != nil {
+				return types.NewHttpError(http.StatusBadRequest, fmt.Sprintf("invalid query parameter %v: %v", name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.Body != nil && r.ContentLength != 0
+	}
+	return false
+}
+
+// hasBodyFields reports whether t has anything to decode from the body,
+// ie: it is not a struct made only of path and query parameters
+func hasBodyFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if isBodyField(t.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBodyField(field reflect.StructField) bool {
+	if !field.IsExported() || field.Tag.Get("json") == "-" {
+		return false
+	}
+	_, path := paramName(field, "path")
+	_, query := paramName(field, "query")
+	return !path && !query
+}
+
+// paramName returns the name in the given tag, eg: `query:"name,required"` is "name"
+func paramName(field reflect.StructField, tag string) (string, bool) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(value, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func setValue(v reflect.Value, raw []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		// accept both ?tag=a&tag=b and ?tag=a,b
+		var values []string
+		for _, r := range raw {
+			values = append(values, strings.Split(r, ",")...)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, r := range values {
+			if err := setScalar(slice.Index(i), r); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	return setScalar(v, raw[0])
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fmt.Errorf("expected an RFC 3339 time")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
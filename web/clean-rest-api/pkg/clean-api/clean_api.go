@@ -2,39 +2,186 @@ package cleanapi
 
 import (
 	"clean-rest-api/types"
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"reflect"
+	"sync"
 
 	"github.com/gorilla/mux"
 )
 
+// ErrorHandler answers an error returned by a handler (after all middlewares ran)
+type ErrorHandler func(*types.Context, error)
+
 type CleanApi struct {
-	Context types.Context
-	Router  *mux.Router
+	Router       *mux.Router
+	ErrorHandler ErrorHandler
+	middlewares  []types.Middleware
+	onStart      []func() error
+	onShutdown   []func(context.Context) error
+	routes       []Route
+
+	// the global middlewares are frozen when the api starts serving, the
+	// chain of every endpoint is then built once
+	mu        sync.Mutex
+	freezeRun sync.Once
+	frozen    bool
+	endpoints []*endpoint
+}
+
+// endpoint is the handler of a route, chain includes the global middlewares
+type endpoint struct {
+	handler types.HttpHandlerFunc
+	chain   types.HttpHandlerFunc
+}
+
+// Route describes a registered route. Request and Response are only set for
+// typed handlers and are used to generate the OpenAPI document.
+type Route struct {
+	Method   string
+	Path     string
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// Registrar is implemented by CleanApi and Group, so typed handlers can be
+// registered on both
+type Registrar interface {
+	register(route Route, httpHandlerFunc types.HttpHandlerFunc, middlewares []types.Middleware)
 }
 
 func New() *CleanApi {
 
 	return &CleanApi{
-		Router: mux.NewRouter(),
+		Router:       mux.NewRouter(),
+		ErrorHandler: DefaultErrorHandler,
 	}
 }
 
-func (c *CleanApi) Get(path string, httpHandlerFunc types.HttpHandlerFunc) {
-	c.Router.HandleFunc(path, WrapHandler(httpHandlerFunc)).Methods("GET")
+// Use registers middlewares that run for every route, including routes
+// registered before the call. It panics once the api serves requests.
+func (c *CleanApi) Use(middlewares ...types.Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		panic("cleanapi: Use called after the api started serving requests")
+	}
+	c.middlewares = append(c.middlewares, middlewares...)
 }
 
-func WrapHandler(hf types.HttpHandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := &types.Context{
-			Writer:  w,
-			Request: r,
+// freeze builds the chain of every endpoint with the global middlewares,
+// it runs once: on Listen or on the first request
+func (c *CleanApi) freeze() {
+	c.freezeRun.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.frozen = true
+		for _, ep := range c.endpoints {
+			ep.chain = Chain(ep.handler, c.middlewares...)
 		}
+	})
+}
+
+// Handle registers a handler for the given method and path. The route
+// middlewares run after the global ones, in the order they are passed.
+func (c *CleanApi) Handle(method string, path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.register(Route{Method: method, Path: path}, httpHandlerFunc, middlewares)
+}
+
+func (c *CleanApi) register(route Route, httpHandlerFunc types.HttpHandlerFunc, middlewares []types.Middleware) {
+	ep := &endpoint{handler: Chain(httpHandlerFunc, middlewares...)}
+	c.mu.Lock()
+	if c.frozen {
+		ep.chain = Chain(ep.handler, c.middlewares...)
+	}
+	c.endpoints = append(c.endpoints, ep)
+	c.mu.Unlock()
+
+	c.Router.HandleFunc(route.Path, func(w http.ResponseWriter, r *http.Request) {
+		c.freeze()
+		ctx := types.NewContext(w, r)
+
+		if err := ep.chain(ctx); err != nil {
+			c.handleError(ctx, err)
+		}
+	}).Methods(route.Method)
+
+	c.routes = append(c.routes, route)
+}
+
+// Routes returns the registered routes in registration order
+func (c *CleanApi) Routes() []Route {
+	return append([]Route{}, c.routes...)
+}
 
-		New().Context = *ctx
+func (c *CleanApi) Get(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.Handle(http.MethodGet, path, httpHandlerFunc, middlewares...)
+}
+
+func (c *CleanApi) Post(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.Handle(http.MethodPost, path, httpHandlerFunc, middlewares...)
+}
+
+func (c *CleanApi) Put(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.Handle(http.MethodPut, path, httpHandlerFunc, middlewares...)
+}
+
+func (c *CleanApi) Patch(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.Handle(http.MethodPatch, path, httpHandlerFunc, middlewares...)
+}
+
+func (c *CleanApi) Delete(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	c.Handle(http.MethodDelete, path, httpHandlerFunc, middlewares...)
+}
+
+func (c *CleanApi) handleError(ctx *types.Context, err error) {
+	if c.ErrorHandler == nil {
+		DefaultErrorHandler(ctx, err)
+		return
+	}
+	c.ErrorHandler(ctx, err)
+}
+
+// DefaultErrorHandler logs the error and, if the handler did not write a
+// response yet, answers with a json body and the status of the error
+func DefaultErrorHandler(ctx *types.Context, err error) {
+	log.Printf("%v %v: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+
+	if ctx.Written() {
+		return
+	}
+
+	var httpErr *types.HttpError
+	if errors.As(err, &httpErr) {
+		ctx.JsonWithStatus(types.ErrorStatus(err), httpErr)
+		return
+	}
+
+	// do not leak internal errors to the client
+	status := http.StatusInternalServerError
+	ctx.JsonWithStatus(status, types.NewHttpError(status, http.StatusText(status)))
+}
+
+// Chain applies middlewares to a HttpHandlerFunc, the first middleware is the outermost
+func Chain(hf types.HttpHandlerFunc, middlewares ...types.Middleware) types.HttpHandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		hf = middlewares[i](hf)
+	}
+	return hf
+}
+
+// WrapHandler turns a HttpHandlerFunc into a http.HandlerFunc, answering
+// errors with the DefaultErrorHandler
+func WrapHandler(hf types.HttpHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := types.NewContext(w, r)
 
 		if err := hf(ctx); err != nil {
-			log.Println("error handled")
+			DefaultErrorHandler(ctx, err)
 		}
 	}
 }
@@ -0,0 +1,118 @@
+package cleanapi_test
+
+import (
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// record returns a middleware appending name to calls before the handler runs
+func record(calls *[]string, name string) types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			*calls = append(*calls, name)
+			return next(ctx)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	hf := cleanapi.Chain(func(ctx *types.Context) error {
+		calls = append(calls, "handler")
+		return nil
+	}, record(&calls, "first"), record(&calls, "second"))
+
+	hf(types.NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)))
+	if got := strings.Join(calls, ","); got != "first,second,handler" {
+		t.Errorf("calls = %v", got)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	var calls []string
+	api := cleanapi.New()
+	api.Use(record(&calls, "global"))
+
+	v1 := api.Group("/v1", record(&calls, "v1"))
+	admin := v1.Group("/admin", record(&calls, "admin"))
+	admin.Use(record(&calls, "admin-use"))
+	admin.Get("/users/{id}", func(ctx *types.Context) error {
+		calls = append(calls, "handler:"+ctx.Param("id"))
+		return nil
+	}, record(&calls, "route"))
+	// Use applies to the routes registered before the call too
+	api.Use(record(&calls, "global-late"))
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/admin/users/7", nil))
+
+	want := "global,global-late,v1,admin,admin-use,route,handler:7"
+	if got := strings.Join(calls, ","); got != want || rec.Code != http.StatusOK {
+		t.Errorf("status %v, calls = %v, want %v", rec.Code, got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/users/7", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("route without the group prefix: status = %v", rec.Code)
+	}
+}
+
+func TestUseAfterServing(t *testing.T) {
+	api := cleanapi.New()
+	api.Get("/ping", func(ctx *types.Context) error { return nil })
+	api.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Use did not panic once the api served a request")
+		}
+	}()
+	api.Use(record(new([]string), "late"))
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	api := cleanapi.New()
+	api.Use(func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			ctx.Writer.Header().Set("X-Global", "1")
+			return next(ctx)
+		}
+	})
+	api.Get("/ping", func(ctx *types.Context) error {
+		ctx.Json("pong")
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ping", nil))
+			if rec.Header().Get("X-Global") != "1" {
+				t.Error("the global middleware did not run")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestErrorStatus(t *testing.T) {
+	api := cleanapi.New()
+	api.Get("/fail", func(ctx *types.Context) error {
+		return types.NewHttpError(http.StatusConflict, "conflict")
+	})
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/fail", nil))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"error":"conflict"`) {
+		t.Errorf("status %v, body %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,155 @@
+// Package cleanapitest runs requests against a CleanApi (or a single
+// handler) in memory and asserts on the response and the returned error.
+//
+//	client := cleanapitest.NewClient(t, api)
+//	client.Post("/messages", map[string]string{"title": "hi"}).
+//		Do().
+//		ExpectStatus(http.StatusCreated).
+//		ExpectJSON("title", "hi")
+package cleanapitest
+
+import (
+	"bytes"
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/types"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+}
+
+// NewClient sends requests through the router of api, with all its routes,
+// middlewares and error handler. It wraps the error handler of api to record
+// the errors, so set the error handler before.
+func NewClient(t testing.TB, api *cleanapi.CleanApi) *Client {
+	handle := api.ErrorHandler
+	if handle == nil {
+		handle = cleanapi.DefaultErrorHandler
+	}
+	api.ErrorHandler = func(ctx *types.Context, err error) {
+		recordError(ctx.Request, err)
+		handle(ctx, err)
+	}
+	return &Client{t: t, handler: api.Router}
+}
+
+// NewHandlerClient sends every request straight to hf (wrapped in the given
+// middlewares), without routing. Use Request.Param to fake path variables.
+func NewHandlerClient(t testing.TB, hf types.HttpHandlerFunc, middlewares ...types.Middleware) *Client {
+	chain := cleanapi.Chain(hf, middlewares...)
+	return &Client{t: t, handler: cleanapi.WrapHandler(func(ctx *types.Context) error {
+		err := chain(ctx)
+		recordError(ctx.Request, err)
+		return err
+	})}
+}
+
+type responseKey struct{}
+
+// recordError stores the error returned by the handler chain in the response
+// of the request
+func recordError(r *http.Request, err error) {
+	if res, ok := r.Context().Value(responseKey{}).(*Response); ok {
+		res.Err = err
+	}
+}
+
+func (c *Client) Get(path string) *Request {
+	return c.NewRequest(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body interface{}) *Request {
+	return c.NewRequest(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body interface{}) *Request {
+	return c.NewRequest(http.MethodPut, path, body)
+}
+
+func (c *Client) Patch(path string, body interface{}) *Request {
+	return c.NewRequest(http.MethodPatch, path, body)
+}
+
+func (c *Client) Delete(path string) *Request {
+	return c.NewRequest(http.MethodDelete, path, nil)
+}
+
+// NewRequest builds a request. A body that is a string or []byte is sent as
+// is, anything else is encoded as json.
+func (c *Client) NewRequest(method string, path string, body interface{}) *Request {
+	c.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	case []byte:
+		reader = bytes.NewBuffer(b)
+	default:
+		content, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatalf("cleanapitest: cannot encode request body: %v", err)
+		}
+		reader = bytes.NewBuffer(content)
+	}
+
+	r := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return &Request{client: c, Request: r}
+}
+
+// Request is a request being built, send it with Do
+type Request struct {
+	client  *Client
+	Request *http.Request
+	params  map[string]string
+}
+
+func (r *Request) Header(name string, value string) *Request {
+	r.Request.Header.Set(name, value)
+	return r
+}
+
+// WithValue makes value available to handlers with ctx.Get(key), as if a
+// middleware had stored it
+func (r *Request) WithValue(key string, value interface{}) *Request {
+	r.Request = r.Request.WithContext(context.WithValue(r.Request.Context(), types.ContextKey(key), value))
+	return r
+}
+
+// Param fakes a path variable for handler clients. Routed requests get their
+// variables from the path, the router overrides the fake ones.
+func (r *Request) Param(name string, value string) *Request {
+	if r.params == nil {
+		r.params = map[string]string{}
+	}
+	r.params[name] = value
+	return r
+}
+
+func (r *Request) Do() *Response {
+	r.client.t.Helper()
+
+	res := &Response{t: r.client.t, Recorder: httptest.NewRecorder()}
+
+	req := r.Request
+	if r.params != nil {
+		req = mux.SetURLVars(req, r.params)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), responseKey{}, res))
+
+	r.client.handler.ServeHTTP(res.Recorder, req)
+	return res
+}
@@ -0,0 +1,135 @@
+package cleanapitest
+
+import (
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/types"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// fakeT records the failures of expectations instead of failing the test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+var errBoom = errors.New("boom")
+
+func newApi() *cleanapi.CleanApi {
+	api := cleanapi.New()
+	api.Get("/items/{id}", func(ctx *types.Context) error {
+		ctx.Json(map[string]interface{}{"id": ctx.Param("id"), "tags": []string{"a", "b"}})
+		return nil
+	})
+	api.Get("/fail", func(ctx *types.Context) error {
+		return &types.HttpError{Code: http.StatusTeapot, Message: "teapot", Err: errBoom}
+	})
+	api.Post("/echo", func(ctx *types.Context) error {
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Writer.Header().Set("Content-Type", ctx.Request.Header.Get("Content-Type"))
+		ctx.Writer.Write(body)
+		return nil
+	})
+	return api
+}
+
+func TestClient(t *testing.T) {
+	client := NewClient(t, newApi())
+
+	client.Get("/items/7").
+		Do().
+		ExpectNoError().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON("id", "7").
+		ExpectJSON("tags.1", "b")
+
+	client.Get("/fail").
+		Do().
+		ExpectStatus(http.StatusTeapot).
+		ExpectError(errBoom).
+		ExpectHttpError(http.StatusTeapot).
+		ExpectJSON("error", "teapot")
+}
+
+func TestClientBodies(t *testing.T) {
+	client := NewClient(t, newApi())
+
+	client.Post("/echo", map[string]int{"n": 1}).
+		Do().
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON("n", 1)
+
+	res := client.Post("/echo", "raw").Do()
+	if body := res.Recorder.Body.String(); body != "raw" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestClientKeepsErrorHandler(t *testing.T) {
+	api := newApi()
+	var handled error
+	api.ErrorHandler = func(ctx *types.Context, err error) {
+		handled = err
+		ctx.Writer.WriteHeader(http.StatusBadGateway)
+	}
+
+	NewClient(t, api).Get("/fail").
+		Do().
+		ExpectStatus(http.StatusBadGateway).
+		ExpectError(errBoom)
+	if !errors.Is(handled, errBoom) {
+		t.Errorf("error handler got %v", handled)
+	}
+}
+
+func TestHandlerClient(t *testing.T) {
+	var calls []string
+	middleware := func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			calls = append(calls, "middleware")
+			return next(ctx)
+		}
+	}
+	hf := func(ctx *types.Context) error {
+		user, _ := ctx.Get("user")
+		return fmt.Errorf("%v %v: %w", user, ctx.Param("id"), errBoom)
+	}
+
+	NewHandlerClient(t, hf, middleware).
+		Get("/anything").
+		Param("id", "42").
+		WithValue("user", "alice").
+		Do().
+		ExpectStatus(http.StatusInternalServerError).
+		ExpectError(errBoom)
+	if len(calls) != 1 {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestFailedExpectations(t *testing.T) {
+	ft := &fakeT{TB: t}
+	NewClient(ft, newApi()).Get("/items/7").
+		Do().
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader("Content-Type", "text/plain").
+		ExpectJSON("id", 7).
+		ExpectJSON("tags.2", "c").
+		ExpectJSON("id.name", "x").
+		ExpectError(errBoom).
+		ExpectHttpError(http.StatusNotFound)
+
+	// every failed expectation is reported, not only the first one
+	if len(ft.failures) != 7 {
+		t.Errorf("failures = %q", ft.failures)
+	}
+}
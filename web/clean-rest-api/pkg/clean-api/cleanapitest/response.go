@@ -0,0 +1,134 @@
+package cleanapitest
+
+import (
+	"clean-rest-api/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response holds the recorded response and the error the handler chain
+// returned. Failed expectations are reported with t.Errorf, so a chain of
+// expectations reports every mismatch.
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+	Err      error
+}
+
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.t.Errorf("expected status %v, got %v (body: %s)", code, r.Recorder.Code, r.Recorder.Body.String())
+	}
+	return r
+}
+
+func (r *Response) ExpectHeader(name string, value string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Header().Get(name); got != value {
+		r.t.Errorf("expected header %v to be %q, got %q", name, value, got)
+	}
+	return r
+}
+
+// ExpectJSON compares the value at path in the json body with want. The path
+// is dot separated with numeric indexes for arrays, eg: "details.0.field".
+// An empty path compares the whole body.
+func (r *Response) ExpectJSON(path string, want interface{}) *Response {
+	r.t.Helper()
+
+	var body interface{}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &body); err != nil {
+		r.t.Errorf("expected a json body, got %q: %v", r.Recorder.Body.String(), err)
+		return r
+	}
+
+	got, err := lookup(body, path)
+	if err != nil {
+		r.t.Errorf("json path %q: %v", path, err)
+		return r
+	}
+
+	// round trip want so that eg: ints compare equal to the decoded float64s
+	content, err := json.Marshal(want)
+	if err != nil {
+		r.t.Fatalf("cleanapitest: cannot encode expected value: %v", err)
+	}
+	var normalized interface{}
+	json.Unmarshal(content, &normalized)
+
+	if !reflect.DeepEqual(got, normalized) {
+		r.t.Errorf("json path %q: expected %v, got %v", path, normalized, got)
+	}
+	return r
+}
+
+func (r *Response) ExpectNoError() *Response {
+	r.t.Helper()
+	if r.Err != nil {
+		r.t.Errorf("expected no error, got %v", r.Err)
+	}
+	return r
+}
+
+// ExpectError checks that the handler returned an error matching target with errors.Is
+func (r *Response) ExpectError(target error) *Response {
+	r.t.Helper()
+	if !errors.Is(r.Err, target) {
+		r.t.Errorf("expected error %v, got %v", target, r.Err)
+	}
+	return r
+}
+
+// ExpectHttpError checks that the handler returned a types.HttpError with the given code
+func (r *Response) ExpectHttpError(code int) *Response {
+	r.t.Helper()
+	var httpErr *types.HttpError
+	if !errors.As(r.Err, &httpErr) {
+		r.t.Errorf("expected an http error with status %v, got %v", code, r.Err)
+	} else if httpErr.Code != code {
+		r.t.Errorf("expected an http error with status %v, got %v (%v)", code, httpErr.Code, httpErr)
+	}
+	return r
+}
+
+// Decode decodes the json body into v
+func (r *Response) Decode(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("cannot decode response body %q: %v", r.Recorder.Body.String(), err)
+	}
+	return r
+}
+
+func lookup(value interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", key)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q in array of length %v", key, len(v))
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("cannot get %q of %v", key, value)
+		}
+	}
+	return value, nil
+}
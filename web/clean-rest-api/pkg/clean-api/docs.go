@@ -0,0 +1,176 @@
+package cleanapi
+
+import (
+	"clean-rest-api/pkg/clean-api/openapi"
+	"clean-rest-api/types"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// matches gorilla path variables, eg: {id} or {id:[0-9]+}
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// ServeDocs serves the generated OpenAPI document at /openapi.json and a
+// docs UI rendering it at /docs. Both are built from the routes registered
+// at request time, so it can be called before or after registering routes.
+func (c *CleanApi) ServeDocs(info openapi.Info) {
+	c.Router.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.OpenAPI(info))
+	}).Methods(http.MethodGet)
+
+	c.Router.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	}).Methods(http.MethodGet)
+}
+
+// OpenAPI generates the OpenAPI 3.1 document of the registered routes
+func (c *CleanApi) OpenAPI(info openapi.Info) *openapi.Document {
+	g := openapi.NewGenerator()
+	errorSchema := g.Schema(reflect.TypeOf(types.HttpError{}))
+
+	doc := &openapi.Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]openapi.PathItem{},
+	}
+
+	for _, route := range c.routes {
+		path := pathVariable.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = openapi.PathItem{}
+		}
+
+		op := operation(g, route, path)
+		op.Responses["default"] = &openapi.Response{
+			Description: "Error",
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: errorSchema}},
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = g.Schemas()
+	return doc
+}
+
+func operation(g *openapi.Generator, route Route, path string) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: operationID(route.Method, path),
+		Responses:   map[string]*openapi.Response{},
+	}
+
+	documented := map[string]bool{}
+	if route.Request != nil {
+		op.Parameters, op.RequestBody = requestDoc(g, route)
+		for _, p := range op.Parameters {
+			if p.In == "path" {
+				documented[p.Name] = true
+			}
+		}
+	}
+
+	// untyped routes (or typed ones without a path field) still document their path variables
+	for _, match := range pathVariable.FindAllStringSubmatch(path, -1) {
+		if !documented[match[1]] {
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+
+	code := successStatus(route.Method, route.Response)
+	status := strconv.Itoa(code)
+	op.Responses[status] = &openapi.Response{Description: http.StatusText(code)}
+	if route.Response != nil && code != http.StatusNoContent {
+		op.Responses[status].Content = map[string]openapi.MediaType{
+			"application/json": {Schema: g.Schema(route.Response)},
+		}
+	}
+
+	return op
+}
+
+func requestDoc(g *openapi.Generator, route Route) ([]openapi.Parameter, *openapi.RequestBody) {
+	t := route.Request
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []openapi.Parameter
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			for _, in := range []string{"path", "query"} {
+				name, ok := paramName(field, in)
+				if !ok {
+					continue
+				}
+				params = append(params, openapi.Parameter{
+					Name:        name,
+					In:          in,
+					Description: field.Tag.Get("doc"),
+					Required:    in == "path" || strings.Contains(field.Tag.Get(in), ",required") || hasRule(field, "required"),
+					Schema:      g.Schema(field.Type),
+				})
+			}
+		}
+	}
+
+	switch route.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return params, nil
+	}
+	if !hasBodyFields(t) {
+		return params, nil
+	}
+
+	schema := g.Schema(route.Request)
+	if len(params) > 0 {
+		// the named schema would also list the parameter fields
+		schema = g.StructSchema(t, func(field reflect.StructField) bool { return !isBodyField(field) })
+	}
+
+	return params, &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]openapi.MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func hasRule(field reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(field.Tag.Get("validate"), ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID builds an id like getMessagesById from GET /messages/{id}
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
@@ -0,0 +1,64 @@
+package cleanapi_test
+
+import (
+	"clean-rest-api/model"
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/pkg/clean-api/openapi"
+	"clean-rest-api/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type getMessage struct {
+	Id int `path:"id"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	api := cleanapi.New()
+	cleanapi.Get(api, "/messages/{id:[0-9]+}", func(ctx *types.Context, req getMessage) (model.Message, error) {
+		return model.Message{Id: req.Id}, nil
+	})
+	api.ServeDocs(openapi.Info{Title: "test", Version: "1"})
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc openapi.Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	op := doc.Paths["/messages/{id}"]["get"]
+	if op == nil {
+		t.Fatalf("paths = %v", doc.Paths)
+	}
+	if op.OperationID != "getMessagesById" || len(op.Parameters) != 1 || op.Parameters[0].Name != "id" {
+		t.Errorf("operation = %+v", op)
+	}
+	ref := op.Responses["200"].Content["application/json"].Schema.Ref
+	if ref != "#/components/schemas/model.Message" {
+		t.Errorf("response ref = %v", ref)
+	}
+	for _, name := range []string{"model.Message", "types.HttpError"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("missing component %v in %v", name, doc.Components.Schemas)
+		}
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	api := cleanapi.New()
+	api.ServeDocs(openapi.Info{Title: "test", Version: "1"})
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v", rec.Code)
+	}
+	// relative, so the page works when the api is mounted under a prefix
+	if body := rec.Body.String(); !strings.Contains(body, `fetch("openapi.json")`) {
+		t.Errorf("docs page does not fetch the relative document")
+	}
+}
@@ -0,0 +1,65 @@
+package cleanapi
+
+import (
+	"clean-rest-api/types"
+	"net/http"
+)
+
+// Group registers routes under a common path prefix with shared middlewares
+type Group struct {
+	api         *CleanApi
+	prefix      string
+	middlewares []types.Middleware
+}
+
+func (c *CleanApi) Group(prefix string, middlewares ...types.Middleware) *Group {
+	return &Group{
+		api:         c,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// Group creates a nested group that inherits the prefix and middlewares of g
+func (g *Group) Group(prefix string, middlewares ...types.Middleware) *Group {
+	return &Group{
+		api:         g.api,
+		prefix:      g.prefix + prefix,
+		middlewares: append(append([]types.Middleware{}, g.middlewares...), middlewares...),
+	}
+}
+
+// Use registers middlewares for the routes registered on the group after the call
+func (g *Group) Use(middlewares ...types.Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+func (g *Group) Handle(method string, path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.register(Route{Method: method, Path: path}, httpHandlerFunc, middlewares)
+}
+
+func (g *Group) register(route Route, httpHandlerFunc types.HttpHandlerFunc, middlewares []types.Middleware) {
+	route.Path = g.prefix + route.Path
+	all := append(append([]types.Middleware{}, g.middlewares...), middlewares...)
+	g.api.register(route, httpHandlerFunc, all)
+}
+
+func (g *Group) Get(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.Handle(http.MethodGet, path, httpHandlerFunc, middlewares...)
+}
+
+func (g *Group) Post(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.Handle(http.MethodPost, path, httpHandlerFunc, middlewares...)
+}
+
+func (g *Group) Put(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.Handle(http.MethodPut, path, httpHandlerFunc, middlewares...)
+}
+
+func (g *Group) Patch(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.Handle(http.MethodPatch, path, httpHandlerFunc, middlewares...)
+}
+
+func (g *Group) Delete(path string, httpHandlerFunc types.HttpHandlerFunc, middlewares ...types.Middleware) {
+	g.Handle(http.MethodDelete, path, httpHandlerFunc, middlewares...)
+}
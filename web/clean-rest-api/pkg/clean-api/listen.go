@@ -0,0 +1,177 @@
+package cleanapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ListenOptions configures the http server started by Listen.
+// Zero values fall back to the defaults below.
+type ListenOptions struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGINT/SIGTERM before the server is closed
+	ShutdownTimeout time.Duration
+	// ShutdownHookTimeout is how long the shutdown hooks get, once the
+	// requests are done, ShutdownTimeout if 0
+	ShutdownHookTimeout time.Duration
+
+	// TLS is enabled when both files are set, setting only one is an error
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// H2C serves HTTP/2 without TLS (eg: behind a proxy that terminates TLS)
+	H2C bool
+}
+
+const (
+	defaultAddr              = ":8080"
+	defaultReadTimeout       = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 15 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultShutdownTimeout   = 10 * time.Second
+)
+
+func (o ListenOptions) withDefaults() ListenOptions {
+	if o.Addr == "" {
+		o.Addr = defaultAddr
+	}
+	if o.ReadTimeout == 0 {
+		o.ReadTimeout = defaultReadTimeout
+	}
+	if o.ReadHeaderTimeout == 0 {
+		o.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = defaultWriteTimeout
+	}
+	if o.IdleTimeout == 0 {
+		o.IdleTimeout = defaultIdleTimeout
+	}
+	if o.ShutdownTimeout == 0 {
+		o.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if o.ShutdownHookTimeout == 0 {
+		o.ShutdownHookTimeout = o.ShutdownTimeout
+	}
+	return o
+}
+
+// OnStart registers a hook that runs before the server starts listening.
+// If a hook fails, Listen returns its error without starting the server.
+func (c *CleanApi) OnStart(hook func() error) {
+	c.onStart = append(c.onStart, hook)
+}
+
+// OnShutdown registers a hook that runs after the server stopped accepting
+// requests and drained the in-flight ones (eg: to close a db pool).
+// Hooks run in reverse order of registration.
+func (c *CleanApi) OnShutdown(hook func(context.Context) error) {
+	c.onShutdown = append(c.onShutdown, hook)
+}
+
+// Listen starts the server and blocks until it is stopped with SIGINT or
+// SIGTERM, then shuts it down gracefully
+func (c *CleanApi) Listen(opts ListenOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return c.ListenContext(ctx, opts)
+}
+
+// ListenContext is like Listen but shuts down when ctx is done
+func (c *CleanApi) ListenContext(ctx context.Context, opts ListenOptions) error {
+	opts = opts.withDefaults()
+	if (opts.TLSCertFile == "") != (opts.TLSKeyFile == "") {
+		return errors.New("cleanapi: tls needs both TLSCertFile and TLSKeyFile")
+	}
+	c.freeze()
+
+	for _, hook := range c.onStart {
+		if err := hook(); err != nil {
+			return fmt.Errorf("start hook: %w", err)
+		}
+	}
+
+	var handler http.Handler = c.Router
+	if opts.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: opts.IdleTimeout})
+	}
+
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadTimeout:       opts.ReadTimeout,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if opts.TLSCertFile != "" && opts.TLSKeyFile != "" {
+			log.Printf("server is running on %v (tls)", opts.Addr)
+			serveErr <- srv.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile)
+		} else {
+			log.Printf("server is running on %v", opts.Addr)
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		// the server could not start (eg: port in use), still run the shutdown hooks
+		return errors.Join(err, c.runShutdownHooks(opts.ShutdownHookTimeout))
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down, waiting for in-flight requests...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		// deadline exceeded, drop the remaining connections
+		srv.Close()
+	}
+
+	// the shutdown may have used the whole deadline, the hooks get their own
+	if hookErr := c.runShutdownHooks(opts.ShutdownHookTimeout); hookErr != nil {
+		err = errors.Join(err, hookErr)
+	}
+
+	if serr := <-serveErr; serr != nil && !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+
+	return err
+}
+
+func (c *CleanApi) runShutdownHooks(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(c.onShutdown) - 1; i >= 0; i-- {
+		if err := c.onShutdown[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package cleanapi_test
+
+import (
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/types"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func waitListening(t *testing.T, addr string) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	t.Fatalf("nothing listens on %v", addr)
+}
+
+func TestListenContext(t *testing.T) {
+	api := cleanapi.New()
+	release := make(chan struct{})
+	api.Get("/slow", func(ctx *types.Context) error {
+		<-release
+		ctx.Json("done")
+		return nil
+	})
+
+	var events []string
+	api.OnStart(func() error {
+		events = append(events, "start")
+		return nil
+	})
+	api.OnShutdown(func(ctx context.Context) error {
+		events = append(events, "first")
+		return nil
+	})
+	api.OnShutdown(func(ctx context.Context) error {
+		// the hooks get their own deadline, whatever the shutdown used
+		if ctx.Err() != nil {
+			t.Errorf("hook context is done: %v", ctx.Err())
+		}
+		events = append(events, "second")
+		return errors.New("db close failed")
+	})
+
+	addr := freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- api.ListenContext(ctx, cleanapi.ListenOptions{Addr: addr, ShutdownTimeout: 200 * time.Millisecond})
+	}()
+
+	waitListening(t, addr)
+
+	// a request still in flight when the shutdown deadline passes
+	requestDone := make(chan struct{})
+	go func() {
+		defer close(requestDone)
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	err := <-done
+	close(release)
+	<-requestDone
+
+	if err == nil || !strings.Contains(err.Error(), "db close failed") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the shutdown and the hook errors", err)
+	}
+	if got := strings.Join(events, ","); got != "start,second,first" {
+		t.Errorf("events = %v", got)
+	}
+}
+
+func TestListenErrors(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	hookFailed := errors.New("hook failed")
+	tests := map[string]struct {
+		opts cleanapi.ListenOptions
+		want string
+	}{
+		"cert without key": {cleanapi.ListenOptions{Addr: freeAddr(t), TLSCertFile: "cert.pem"}, "TLSKeyFile"},
+		"key without cert": {cleanapi.ListenOptions{Addr: freeAddr(t), TLSKeyFile: "key.pem"}, "TLSCertFile"},
+		// the hooks still run and their error is kept
+		"address in use": {cleanapi.ListenOptions{Addr: l.Addr().String()}, "hook failed"},
+	}
+	for name, test := range tests {
+		api := cleanapi.New()
+		api.OnShutdown(func(ctx context.Context) error { return hookFailed })
+
+		err := api.ListenContext(context.Background(), test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: error = %v, want %q", name, err, test.want)
+		}
+	}
+}
+
+func TestStartHookFails(t *testing.T) {
+	api := cleanapi.New()
+	api.OnStart(func() error { return errors.New("no db") })
+
+	err := api.ListenContext(context.Background(), cleanapi.ListenOptions{Addr: freeAddr(t)})
+	if err == nil || !strings.Contains(err.Error(), "no db") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"net/http"
+)
+
+// Adapt turns a standard net/http middleware (eg: an existing auth or cors
+// middleware) into a cleanapi middleware. The error of the wrapped handler
+// is passed through, so the chain stays error-returning.
+func Adapt(mw func(http.Handler) http.Handler) types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			var err error
+			writer := ctx.Writer
+			tracked := types.NewResponseWriter(writer)
+
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the middleware may have replaced the writer or the request (eg: with new
+				// context values), what the handler writes to it is still recorded in tracked
+				ctx.Writer = tracked.Track(w)
+				ctx.Request = r
+				err = next(ctx)
+			})).ServeHTTP(tracked, ctx.Request)
+
+			// the wrapper is done, errors are answered through the outer writer
+			ctx.Writer = writer
+			return err
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// discardBody is a net/http middleware whose writer drops the body, like a
+// middleware answering HEAD requests or buffering the response
+func discardBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(discardWriter{w}, r)
+	})
+}
+
+type discardWriter struct {
+	http.ResponseWriter
+}
+
+func (w discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func TestAdapt(t *testing.T) {
+	withUser := Adapt(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Adapted", "1")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), types.ContextKey("user"), "ana")))
+		})
+	})
+
+	failed := errors.New("failed")
+	rec := httptest.NewRecorder()
+	ctx := types.NewContext(rec, httptest.NewRequest("GET", "/", nil))
+	err := withUser(func(ctx *types.Context) error {
+		if user, _ := ctx.Get("user"); user != "ana" {
+			t.Errorf("user = %v", user)
+		}
+		return failed
+	})(ctx)
+
+	// the error of the handler goes through the net/http middleware
+	if err != failed || rec.Header().Get("X-Adapted") != "1" {
+		t.Errorf("error %v, headers %v", err, rec.Header())
+	}
+	if _, ok := ctx.Writer.(*types.ResponseWriter); !ok || ctx.Written() {
+		t.Errorf("writer %T, written %v", ctx.Writer, ctx.Written())
+	}
+}
+
+func TestAdaptWrittenThroughWrapper(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := types.NewContext(rec, httptest.NewRequest("GET", "/", nil))
+	Adapt(discardBody)(func(ctx *types.Context) error {
+		ctx.Writer.Write([]byte("dropped"))
+		return nil
+	})(ctx)
+
+	// nothing reached the recorder, but the handler did answer: the error
+	// handler must not write a second response
+	if !ctx.Written() || rec.Body.Len() != 0 {
+		t.Errorf("written %v, body %q", ctx.Written(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ctx = types.NewContext(rec, httptest.NewRequest("GET", "/", nil))
+	Adapt(discardBody)(func(ctx *types.Context) error {
+		ctx.Writer.WriteHeader(http.StatusAccepted)
+		return nil
+	})(ctx)
+	if ctx.Status() != http.StatusAccepted || rec.Code != http.StatusAccepted {
+		t.Errorf("status %v, recorded %v", ctx.Status(), rec.Code)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"net/http"
+	"strings"
+)
+
+const userKey = "user"
+
+// BearerAuth validates the bearer token of the request with validate and
+// stores the returned user in the context. Requests without a valid token
+// are answered with a 401.
+func BearerAuth(validate func(token string) (interface{}, error)) types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			authHeader := ctx.Request.Header.Get("Authorization")
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				return types.NewHttpError(http.StatusUnauthorized, "missing bearer token")
+			}
+
+			user, err := validate(strings.TrimPrefix(authHeader, "Bearer "))
+			if err != nil {
+				return types.NewHttpError(http.StatusUnauthorized, "invalid token")
+			}
+
+			ctx.Set(userKey, user)
+			return next(ctx)
+		}
+	}
+}
+
+// GetUser returns the user stored by BearerAuth
+func GetUser(ctx *types.Context) (interface{}, bool) {
+	return ctx.Get(userKey)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"log"
+	"time"
+)
+
+// Logger logs every request with its status, the time it took and the
+// error returned by the handler, if any
+func Logger() types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			start := time.Now()
+
+			err := next(ctx)
+
+			status := ctx.Status()
+			if err != nil && !ctx.Written() {
+				status = types.ErrorStatus(err)
+			}
+
+			if err != nil {
+				log.Printf("%v %v %v %v error: %v", ctx.Request.Method, ctx.Request.URL.Path, status, time.Since(start), err)
+			} else {
+				log.Printf("%v %v %v %v", ctx.Request.Method, ctx.Request.URL.Path, status, time.Since(start))
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// Recover turns a panic in the handler into an error, so it reaches the
+// error handler like any other failure
+func Recover() types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("panic recovered: %v\n%s", r, debug.Stack())
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"clean-rest-api/types"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "requestId"
+)
+
+// RequestID reuses the X-Request-ID header of the request or generates a new
+// id, stores it in the context and echoes it back in the response
+func RequestID() types.Middleware {
+	return func(next types.HttpHandlerFunc) types.HttpHandlerFunc {
+		return func(ctx *types.Context) error {
+			id := ctx.Request.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+
+			ctx.Set(requestIDKey, id)
+			ctx.Writer.Header().Set(RequestIDHeader, id)
+
+			return next(ctx)
+		}
+	}
+}
+
+// GetRequestID returns the id stored by the RequestID middleware
+func GetRequestID(ctx *types.Context) string {
+	id, _ := ctx.Get(requestIDKey)
+	s, _ := id.(string)
+	return s
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generator turns go types into json schemas. Named structs are stored once
+// under components/schemas and referenced with $ref.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	types   map[string]reflect.Type
+}
+
+func NewGenerator() *Generator {
+	return &Generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}, types: map[string]reflect.Type{}}
+}
+
+// Schemas returns the component schemas collected so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema returns the schema of t, a $ref for named structs
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	s := g.schema(t)
+	if nullable && s.Ref == "" {
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+	}
+	return s
+}
+
+// StructSchema returns the inline object schema of a struct, leaving out the
+// fields for which skip returns true (eg: fields bound from the path)
+func (g *Generator) StructSchema(t reflect.Type, skip func(reflect.StructField) bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t, skip)
+	return s
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.StructSchema(t, nil)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			// register before walking the fields so recursive types terminate
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.StructSchema(t, nil)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// interfaces and anything else accept any value
+	return &Schema{}
+}
+
+var (
+	// package paths in the type arguments of generic types
+	packagePath = regexp.MustCompile(`[^\[\],* ]*/`)
+	// component names must match ^[a-zA-Z0-9._-]+$
+	invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// componentName names the component of t after its package and type, eg:
+// model.Message or model.Page_model.Message for model.Page[model.Message].
+// The full package path is used if another type already took the name, and
+// a number is added for types declared in functions, that share it too.
+func (g *Generator) componentName(t reflect.Type) string {
+	typeName := packagePath.ReplaceAllString(t.Name(), "")
+	name := sanitizeName(path.Base(t.PkgPath()) + "." + typeName)
+	if _, taken := g.types[name]; taken {
+		name = sanitizeName(t.PkgPath() + "." + typeName)
+	}
+	for i, base := 2, name; g.types[name] != nil; i++ {
+		name = base + "_" + strconv.Itoa(i)
+	}
+
+	g.names[t] = name
+	g.types[name] = t
+	return name
+}
+
+func sanitizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+func (g *Generator) addFields(s *Schema, t reflect.Type, skip func(reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || (skip != nil && skip(field)) {
+			continue
+		}
+
+		name, omitempty, ok := JsonName(field)
+		if !ok {
+			continue
+		}
+
+		// embedded structs without a json name are flattened like encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(s, field.Type, skip)
+			continue
+		}
+
+		fs := g.Schema(field.Type)
+		if doc := field.Tag.Get("doc"); doc != "" {
+			if fs.Ref != "" {
+				// siblings of $ref are allowed in 3.1
+				fs = &Schema{Ref: fs.Ref, Description: doc}
+			} else {
+				fs.Description = doc
+			}
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, v := range strings.Split(enum, ",") {
+				fs.Enum = append(fs.Enum, v)
+			}
+		}
+
+		s.Properties[name] = fs
+		if !omitempty && field.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// JsonName returns the name encoding/json uses for a field, whether it has
+// the omitempty option and false if the field is skipped
+func JsonName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(opts, "omitempty"), true
+}
@@ -0,0 +1,133 @@
+package openapi_test
+
+import (
+	"clean-rest-api/model"
+	"clean-rest-api/pkg/clean-api/openapi"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type page[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next,omitempty"`
+}
+
+type Base struct {
+	Id int `json:"id"`
+}
+
+type node struct {
+	Base
+	Name     string            `json:"name" doc:"the name"`
+	Kind     string            `json:"kind,omitempty" enum:"a,b"`
+	Parent   *node             `json:"parent"`
+	Children []node            `json:"children"`
+	Created  time.Time         `json:"created"`
+	Size     *int64            `json:"size"`
+	Data     []byte            `json:"data"`
+	Labels   map[string]string `json:"labels"`
+	Ignored  string            `json:"-"`
+}
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+func TestSchema(t *testing.T) {
+	g := openapi.NewGenerator()
+	ref := g.Schema(reflect.TypeOf(&node{}))
+	if ref.Ref != "#/components/schemas/openapi_test.node" {
+		t.Fatalf("ref = %v", ref.Ref)
+	}
+
+	s := g.Schemas()["openapi_test.node"]
+	if s == nil {
+		t.Fatalf("schemas = %v", g.Schemas())
+	}
+	for _, name := range []string{"id", "name", "kind", "parent", "children", "created", "size", "data", "labels"} {
+		if s.Properties[name] == nil {
+			t.Errorf("missing property %v", name)
+		}
+	}
+	if len(s.Properties) != 9 {
+		t.Errorf("properties = %v", s.Properties)
+	}
+	// the recursive field references the schema being built
+	if got := s.Properties["parent"].Ref; got != ref.Ref {
+		t.Errorf("parent = %v", got)
+	}
+	if got := s.Properties["children"].Items.Ref; got != ref.Ref {
+		t.Errorf("children = %v", got)
+	}
+	if got := s.Properties["size"].Type; !reflect.DeepEqual(got, []string{"integer", "null"}) {
+		t.Errorf("size type = %v", got)
+	}
+	if got := s.Properties["created"].Format; got != "date-time" {
+		t.Errorf("created format = %v", got)
+	}
+	if got := s.Properties["data"].Format; got != "byte" {
+		t.Errorf("data format = %v", got)
+	}
+	if got := s.Properties["labels"].AdditionalProperties.Type; got != "string" {
+		t.Errorf("labels = %v", got)
+	}
+	if got := s.Properties["name"].Description; got != "the name" {
+		t.Errorf("name description = %v", got)
+	}
+	if got := s.Properties["kind"].Enum; !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Errorf("kind enum = %v", got)
+	}
+	// pointers and omitempty fields are optional
+	if want := []string{"id", "name", "children", "created", "data", "labels"}; !reflect.DeepEqual(s.Required, want) {
+		t.Errorf("required = %v, want %v", s.Required, want)
+	}
+}
+
+func TestSchemaNames(t *testing.T) {
+	top := reflect.TypeOf(Message{})
+	type Message struct {
+		Local bool `json:"local"`
+	}
+	local := reflect.TypeOf(Message{})
+	var nested reflect.Type
+	{
+		type Message struct{}
+		nested = reflect.TypeOf(Message{})
+	}
+
+	g := openapi.NewGenerator()
+	tests := []struct {
+		typ  reflect.Type
+		want string
+	}{
+		{reflect.TypeOf(model.Message{}), "model.Message"},
+		{top, "openapi_test.Message"},
+		{local, "clean-rest-api_pkg_clean-api_openapi_test.Message"},
+		{nested, "clean-rest-api_pkg_clean-api_openapi_test.Message_2"},
+		{reflect.TypeOf(page[model.Message]{}), "openapi_test.page_model.Message"},
+		{reflect.TypeOf(page[*Base]{}), "openapi_test.page_openapi_test.Base"},
+		// types keep the name they were given first
+		{top, "openapi_test.Message"},
+	}
+	for _, test := range tests {
+		if got := g.Schema(test.typ).Ref; got != "#/components/schemas/"+test.want {
+			t.Errorf("%v: ref = %v, want %v", test.typ, got, test.want)
+		}
+	}
+
+	if len(g.Schemas()) != 7 {
+		t.Errorf("schemas = %v", g.Schemas())
+	}
+	for name := range g.Schemas() {
+		if !validName.MatchString(name) {
+			t.Errorf("invalid component name %v", name)
+		}
+	}
+	if items := g.Schemas()["openapi_test.page_model.Message"].Properties["items"]; items.Items.Ref != "#/components/schemas/model.Message" {
+		t.Errorf("items = %+v", items.Items)
+	}
+}
@@ -0,0 +1,95 @@
+package cleanapi
+
+import (
+	"clean-rest-api/types"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// TypedHandlerFunc receives the request already decoded and validated into
+// Req and returns the value to send back as json
+type TypedHandlerFunc[Req, Res any] func(*types.Context, Req) (Res, error)
+
+// NoContent can be used as Res for handlers that answer with a 204 and no body
+type NoContent struct{}
+
+// Handle registers a typed handler for a pattern like "POST /messages".
+// Req is bound from the json body, path and query, then validated; Res is
+// encoded as json and errors go through the error handler of the api.
+func Handle[Req, Res any](r Registrar, pattern string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("cleanapi: invalid pattern %q, expected \"METHOD /path\"", pattern))
+	}
+	handleTyped(r, strings.ToUpper(method), strings.TrimSpace(path), fn, middlewares)
+}
+
+func Get[Req, Res any](r Registrar, path string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	handleTyped(r, http.MethodGet, path, fn, middlewares)
+}
+
+func Post[Req, Res any](r Registrar, path string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	handleTyped(r, http.MethodPost, path, fn, middlewares)
+}
+
+func Put[Req, Res any](r Registrar, path string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	handleTyped(r, http.MethodPut, path, fn, middlewares)
+}
+
+func Patch[Req, Res any](r Registrar, path string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	handleTyped(r, http.MethodPatch, path, fn, middlewares)
+}
+
+func Delete[Req, Res any](r Registrar, path string, fn TypedHandlerFunc[Req, Res], middlewares ...types.Middleware) {
+	handleTyped(r, http.MethodDelete, path, fn, middlewares)
+}
+
+func handleTyped[Req, Res any](r Registrar, method string, path string, fn TypedHandlerFunc[Req, Res], middlewares []types.Middleware) {
+	route := Route{
+		Method:   method,
+		Path:     path,
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Res)(nil)).Elem(),
+	}
+	check, err := newValidator(route.Request)
+	if err != nil {
+		panic(fmt.Sprintf("cleanapi: %v %v: %v", method, path, err))
+	}
+
+	r.register(route, func(ctx *types.Context) error {
+		var req Req
+		if err := bind(ctx, &req); err != nil {
+			return err
+		}
+		if err := check.validate(&req); err != nil {
+			return err
+		}
+
+		res, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		status := successStatus(method, route.Response)
+		if status == http.StatusNoContent {
+			ctx.Writer.WriteHeader(status)
+			return nil
+		}
+		ctx.JsonWithStatus(status, res)
+		return nil
+	}, middlewares)
+}
+
+var noContentType = reflect.TypeOf(NoContent{})
+
+func successStatus(method string, response reflect.Type) int {
+	if response == noContentType {
+		return http.StatusNoContent
+	}
+	if method == http.MethodPost {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
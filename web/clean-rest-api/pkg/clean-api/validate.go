@@ -0,0 +1,260 @@
+package cleanapi
+
+import (
+	"clean-rest-api/pkg/clean-api/openapi"
+	"clean-rest-api/types"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes why a field of the request failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validator checks the `validate` tags of a request struct. Supported rules:
+// required, min=n, max=n (length for strings, slices and maps, value for
+// numbers) and oneof=a b c. Fields without required skip their rules when
+// they are nil or empty.
+type validator []fieldRules
+
+type fieldRules struct {
+	index    []int
+	name     string
+	required bool
+	rules    []rule
+}
+
+type rule struct {
+	name    string
+	arg     string
+	limit   float64
+	options []string
+}
+
+// newValidator parses the rules of t once, when the route is registered, so
+// invalid rules fail there and not while serving a request
+func newValidator(t reflect.Type) (validator, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	var v validator
+	if err := v.addFields(t, nil); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *validator) addFields(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := v.addFields(field.Type, fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		fr := fieldRules{index: fieldIndex, name: fieldName(field)}
+		for _, r := range strings.Split(tag, ",") {
+			parsed, err := parseRule(r, field.Type)
+			if err != nil {
+				return fmt.Errorf("field %v: %w", field.Name, err)
+			}
+			if parsed.name == "required" {
+				fr.required = true
+			}
+			fr.rules = append(fr.rules, parsed)
+		}
+		*v = append(*v, fr)
+	}
+	return nil
+}
+
+func parseRule(r string, t reflect.Type) (rule, error) {
+	name, arg, _ := strings.Cut(r, "=")
+	parsed := rule{name: name, arg: arg}
+
+	switch name {
+	case "required":
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid validate rule %q", r)
+		}
+		if !measurable(t) {
+			return rule{}, fmt.Errorf("validate rule %q does not apply to %v", r, t)
+		}
+		parsed.limit = limit
+	case "oneof":
+		parsed.options = strings.Fields(arg)
+		if len(parsed.options) == 0 {
+			return rule{}, fmt.Errorf("invalid validate rule %q", r)
+		}
+	default:
+		return rule{}, fmt.Errorf("unknown validate rule %q", r)
+	}
+	return parsed, nil
+}
+
+// validate returns a 422 listing every invalid field of req
+func (v validator) validate(req interface{}) error {
+	rv := reflect.ValueOf(req)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []FieldError
+	for _, field := range v {
+		fv := rv.FieldByIndex(field.index)
+		if !field.required && empty(fv) {
+			continue
+		}
+		for _, r := range field.rules {
+			if msg := r.check(fv); msg != "" {
+				errs = append(errs, FieldError{Field: field.name, Message: msg})
+				// report one problem per field
+				break
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &types.HttpError{
+		Code:    http.StatusUnprocessableEntity,
+		Message: "validation failed",
+		Details: errs,
+	}
+}
+
+// empty reports nil pointers and empty strings and collections, which
+// optional fields leave out
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// fieldName is the name the client used for the field
+func fieldName(field reflect.StructField) string {
+	if name, ok := paramName(field, "path"); ok {
+		return name
+	}
+	if name, ok := paramName(field, "query"); ok {
+		return name
+	}
+	name, _, _ := openapi.JsonName(field)
+	return name
+}
+
+func (r rule) check(v reflect.Value) string {
+	switch r.name {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		size, unit, ok := measure(v)
+		if !ok {
+			return ""
+		}
+		if r.name == "min" && size < r.limit {
+			if unit != "" {
+				return fmt.Sprintf("must have at least %v %v", r.arg, unit)
+			}
+			return fmt.Sprintf("must be at least %v", r.arg)
+		}
+		if r.name == "max" && size > r.limit {
+			if unit != "" {
+				return fmt.Sprintf("must have at most %v %v", r.arg, unit)
+			}
+			return fmt.Sprintf("must be at most %v", r.arg)
+		}
+	case "oneof":
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		value := fmt.Sprint(v.Interface())
+		for _, option := range r.options {
+			if value == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %v", strings.Join(r.options, ", "))
+	}
+	return ""
+}
+
+// measurable reports whether min and max apply to values of t
+func measurable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// measure returns the length of strings and collections (with its unit) or
+// the value of numbers
+func measure(v reflect.Value) (float64, string, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, "", false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
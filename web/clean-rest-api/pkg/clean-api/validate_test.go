@@ -0,0 +1,122 @@
+package cleanapi_test
+
+import (
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Paging struct {
+	Offset int `json:"offset" validate:"max=100"`
+}
+
+type createRequest struct {
+	Paging
+	Title    string   `json:"title" validate:"required,max=5"`
+	Status   *string  `json:"status" validate:"oneof=open closed"`
+	Kind     string   `json:"kind" validate:"oneof=a b"`
+	Tags     []string `json:"tags" validate:"min=1,max=2"`
+	Priority *int     `json:"priority" validate:"min=1,max=3"`
+}
+
+func postCreate(t *testing.T, body string) (int, []cleanapi.FieldError) {
+	api := cleanapi.New()
+	cleanapi.Post(api, "/items", func(ctx *types.Context, req createRequest) (cleanapi.NoContent, error) {
+		return cleanapi.NoContent{}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("POST", "/items", strings.NewReader(body)))
+
+	var res struct {
+		Details []cleanapi.FieldError `json:"details"`
+	}
+	json.NewDecoder(rec.Body).Decode(&res)
+	return rec.Code, res.Details
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		body   string
+		fields []string
+	}{
+		// optional fields left out or empty skip their rules
+		"optional omitted": {`{"title":"a"}`, nil},
+		"optional empty":   {`{"title":"a","kind":"","tags":[],"status":null}`, nil},
+		"valid":            {`{"offset":10,"title":"abc","status":"open","kind":"b","tags":["x"],"priority":3}`, nil},
+		"required":         {`{}`, []string{"title"}},
+		"invalid":          {`{"offset":101,"title":"abcdef","status":"done","kind":"c","tags":["x","y","z"],"priority":0}`, []string{"offset", "title", "status", "kind", "tags", "priority"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			status, details := postCreate(t, test.body)
+			if len(test.fields) == 0 {
+				if status != http.StatusNoContent {
+					t.Errorf("status = %v, details = %+v", status, details)
+				}
+				return
+			}
+
+			if status != http.StatusUnprocessableEntity || len(details) != len(test.fields) {
+				t.Fatalf("status = %v, details = %+v, want %v", status, details, test.fields)
+			}
+			for i, field := range test.fields {
+				if details[i].Field != field {
+					t.Errorf("details[%v] = %+v, want %v", i, details[i], field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateInvalidRules(t *testing.T) {
+	tests := map[string]func(api *cleanapi.CleanApi){
+		"unknown rule": func(api *cleanapi.CleanApi) {
+			type request struct {
+				Name string `json:"name" validate:"requird"`
+			}
+			cleanapi.Post(api, "/a", func(ctx *types.Context, req request) (cleanapi.NoContent, error) { return cleanapi.NoContent{}, nil })
+		},
+		"bad limit": func(api *cleanapi.CleanApi) {
+			type request struct {
+				Name string `json:"name" validate:"max=ten"`
+			}
+			cleanapi.Post(api, "/a", func(ctx *types.Context, req request) (cleanapi.NoContent, error) { return cleanapi.NoContent{}, nil })
+		},
+		"limit on a bool": func(api *cleanapi.CleanApi) {
+			type request struct {
+				Done bool `json:"done" validate:"min=1"`
+			}
+			cleanapi.Post(api, "/a", func(ctx *types.Context, req request) (cleanapi.NoContent, error) { return cleanapi.NoContent{}, nil })
+		},
+	}
+
+	for name, register := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("registering the route did not panic")
+				}
+			}()
+			register(cleanapi.New())
+		})
+	}
+}
+
+func TestErrorWithoutCode(t *testing.T) {
+	api := cleanapi.New()
+	api.Get("/fail", func(ctx *types.Context) error {
+		return &types.HttpError{Message: "no code"}
+	})
+
+	rec := httptest.NewRecorder()
+	api.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/fail", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %v", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"clean-rest-api/model"
+	"context"
+	"sort"
+	"sync"
+)
+
+type InMemoryMessageRepository struct {
+	mu       sync.RWMutex
+	messages map[int]model.Message
+	nextID   int
+}
+
+func NewInMemoryMessageRepository() *InMemoryMessageRepository {
+	return &InMemoryMessageRepository{
+		messages: map[int]model.Message{},
+		nextID:   1,
+	}
+}
+
+func (r *InMemoryMessageRepository) GetAll(ctx context.Context) ([]model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	messages := make([]model.Message, 0, len(r.messages))
+	for _, m := range r.messages {
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Id < messages[j].Id })
+	return messages, nil
+}
+
+func (r *InMemoryMessageRepository) GetByID(ctx context.Context, id int) (*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.messages[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &m, nil
+}
+
+func (r *InMemoryMessageRepository) Create(ctx context.Context, message *model.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message.Id = r.nextID
+	r.nextID++
+	r.messages[message.Id] = *message
+	return nil
+}
+
+func (r *InMemoryMessageRepository) Update(ctx context.Context, id int, message *model.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[id]; !ok {
+		return ErrNotFound
+	}
+	message.Id = id
+	r.messages[id] = *message
+	return nil
+}
+
+func (r *InMemoryMessageRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.messages, id)
+	return nil
+}
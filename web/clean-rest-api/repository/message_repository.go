@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"clean-rest-api/model"
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("message not found")
+
+// MessageRepository stores messages. Create assigns the id of the message,
+// GetByID, Update and Delete return ErrNotFound for unknown ids.
+type MessageRepository interface {
+	GetAll(ctx context.Context) ([]model.Message, error)
+	GetByID(ctx context.Context, id int) (*model.Message, error)
+	Create(ctx context.Context, message *model.Message) error
+	Update(ctx context.Context, id int, message *model.Message) error
+	Delete(ctx context.Context, id int) error
+}
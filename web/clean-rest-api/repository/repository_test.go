@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"clean-rest-api/model"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// repositories returns a constructor for each implementation, so every test
+// runs against all of them
+func repositories(t *testing.T) map[string]func() MessageRepository {
+	return map[string]func() MessageRepository{
+		"memory": func() MessageRepository {
+			return NewInMemoryMessageRepository()
+		},
+		"sqlite": func() MessageRepository {
+			db, err := OpenSQLite(filepath.Join(t.TempDir(), "messages.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			repo, err := NewSQLiteMessageRepository(context.Background(), db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		},
+	}
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			all, err := repo.GetAll(ctx)
+			if err != nil || all == nil || len(all) != 0 {
+				t.Fatalf("GetAll = %v, %v, want an empty list", all, err)
+			}
+
+			first := &model.Message{Title: "first", Body: "a"}
+			second := &model.Message{Title: "second", Body: "b"}
+			for _, m := range []*model.Message{first, second} {
+				if err := repo.Create(ctx, m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if first.Id == 0 || second.Id <= first.Id {
+				t.Fatalf("ids = %v, %v", first.Id, second.Id)
+			}
+
+			got, err := repo.GetByID(ctx, second.Id)
+			if err != nil || *got != *second {
+				t.Errorf("GetByID = %+v, %v, want %+v", got, err, second)
+			}
+
+			update := &model.Message{Title: "updated", Body: "c"}
+			if err := repo.Update(ctx, first.Id, update); err != nil {
+				t.Fatal(err)
+			}
+			if update.Id != first.Id {
+				t.Errorf("updated id = %v, want %v", update.Id, first.Id)
+			}
+
+			all, err = repo.GetAll(ctx)
+			if want := []model.Message{*update, *second}; err != nil || !reflect.DeepEqual(all, want) {
+				t.Errorf("GetAll = %+v, %v, want %+v", all, err, want)
+			}
+
+			if err := repo.Delete(ctx, first.Id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.GetByID(ctx, first.Id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByID after Delete: err = %v", err)
+			}
+		})
+	}
+}
+
+func TestRepositoryNotFound(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			if _, err := repo.GetByID(ctx, 42); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetByID: err = %v", err)
+			}
+			if err := repo.Update(ctx, 42, &model.Message{Title: "x"}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update: err = %v", err)
+			}
+			if err := repo.Delete(ctx, 42); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete: err = %v", err)
+			}
+		})
+	}
+}
+
+func TestRepositoryIdsNotReused(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			deleted := &model.Message{Title: "deleted"}
+			repo.Create(ctx, deleted)
+			if err := repo.Delete(ctx, deleted.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			created := &model.Message{Title: "created"}
+			if err := repo.Create(ctx, created); err != nil {
+				t.Fatal(err)
+			}
+			if created.Id <= deleted.Id {
+				t.Errorf("id %v of a deleted message reused", created.Id)
+			}
+		})
+	}
+}
+
+func TestRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			m := &model.Message{Title: "original"}
+			repo.Create(ctx, m)
+			m.Title = "changed by the caller"
+
+			got, _ := repo.GetByID(ctx, m.Id)
+			got.Title = "changed again"
+			if got, _ := repo.GetByID(ctx, m.Id); got.Title != "original" {
+				t.Errorf("title = %q", got.Title)
+			}
+		})
+	}
+}
+
+func TestRepositoryConcurrentCreates(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := repo.Create(ctx, &model.Message{Title: "concurrent"}); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			all, err := repo.GetAll(ctx)
+			if err != nil || len(all) != 20 {
+				t.Errorf("GetAll = %v messages, %v", len(all), err)
+			}
+		})
+	}
+}
+
+func TestSQLiteReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "messages.db")
+
+	db, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewSQLiteMessageRepository(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &model.Message{Title: "kept", Body: "on disk"}
+	repo.Create(ctx, m)
+	db.Close()
+
+	db, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// creating the table again keeps the existing rows
+	repo, err = NewSQLiteMessageRepository(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetByID(ctx, m.Id); err != nil || *got != *m {
+		t.Errorf("GetByID = %+v, %v, want %+v", got, err, m)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"clean-rest-api/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLiteMessageRepository struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (or creates) the sqlite database at path
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// sqlite allows a single writer, serialise access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, nil
+}
+
+// NewSQLiteMessageRepository creates the messages table if it does not exist yet
+func NewSQLiteMessageRepository(ctx context.Context, db *sql.DB) (*SQLiteMessageRepository, error) {
+	query := `CREATE TABLE IF NOT EXISTS messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		body TEXT NOT NULL
+	)`
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to create messages table: %w", err)
+	}
+	return &SQLiteMessageRepository{db: db}, nil
+}
+
+func (r *SQLiteMessageRepository) GetAll(ctx context.Context) ([]model.Message, error) {
+	query := `SELECT id, title, body FROM messages ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []model.Message{}
+	for rows.Next() {
+		var m model.Message
+		if err := rows.Scan(&m.Id, &m.Title, &m.Body); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *SQLiteMessageRepository) GetByID(ctx context.Context, id int) (*model.Message, error) {
+	query := `SELECT id, title, body FROM messages WHERE id = ?`
+	var m model.Message
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&m.Id, &m.Title, &m.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *SQLiteMessageRepository) Create(ctx context.Context, message *model.Message) error {
+	query := `INSERT INTO messages (title, body) VALUES (?, ?)`
+	result, err := r.db.ExecContext(ctx, query, message.Title, message.Body)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	message.Id = int(id)
+	return nil
+}
+
+func (r *SQLiteMessageRepository) Update(ctx context.Context, id int, message *model.Message) error {
+	query := `UPDATE messages SET title = ?, body = ? WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, message.Title, message.Body, id)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result); err != nil {
+		return err
+	}
+	message.Id = id
+	return nil
+}
+
+func (r *SQLiteMessageRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM messages WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+func expectRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
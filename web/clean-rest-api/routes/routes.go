@@ -1 +1,16 @@
 package routes
+
+import (
+	"clean-rest-api/handlers"
+	cleanapi "clean-rest-api/pkg/clean-api"
+)
+
+func RegisterRoutes(c *cleanapi.CleanApi, messageHandler *handlers.MessageHandler) {
+	messages := c.Group("/messages")
+
+	cleanapi.Get(messages, "", messageHandler.GetAllMessages)
+	cleanapi.Post(messages, "", messageHandler.CreateMessage)
+	cleanapi.Get(messages, "/{id:[0-9]+}", messageHandler.GetMessage)
+	cleanapi.Put(messages, "/{id:[0-9]+}", messageHandler.UpdateMessage)
+	cleanapi.Delete(messages, "/{id:[0-9]+}", messageHandler.DeleteMessage)
+}
@@ -0,0 +1,73 @@
+package routes_test
+
+import (
+	"clean-rest-api/handlers"
+	cleanapi "clean-rest-api/pkg/clean-api"
+	"clean-rest-api/pkg/clean-api/cleanapitest"
+	"clean-rest-api/repository"
+	"clean-rest-api/routes"
+	"clean-rest-api/types"
+	"net/http"
+	"testing"
+)
+
+func newClient(t *testing.T) *cleanapitest.Client {
+	c := cleanapi.New()
+	routes.RegisterRoutes(c, handlers.NewMessageHandler(repository.NewInMemoryMessageRepository()))
+	return cleanapitest.NewClient(t, c)
+}
+
+func TestMessageCrud(t *testing.T) {
+	client := newClient(t)
+
+	client.Post("/messages", map[string]string{"title": "demo", "body": "demo message"}).
+		Do().
+		ExpectNoError().
+		ExpectStatus(http.StatusCreated).
+		ExpectJSON("id", 1).
+		ExpectJSON("title", "demo")
+
+	client.Put("/messages/1", map[string]string{"title": "updated"}).
+		Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON("title", "updated")
+
+	client.Get("/messages").
+		Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON("0.title", "updated")
+
+	client.Delete("/messages/1").
+		Do().
+		ExpectStatus(http.StatusNoContent)
+
+	client.Get("/messages/1").
+		Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectError(repository.ErrNotFound).
+		ExpectHttpError(http.StatusNotFound)
+}
+
+func TestCreateMessageValidation(t *testing.T) {
+	newClient(t).Post("/messages", map[string]string{"body": "no title"}).
+		Do().
+		ExpectStatus(http.StatusUnprocessableEntity).
+		ExpectJSON("details.0.field", "title")
+}
+
+func TestHandlerClient(t *testing.T) {
+	hf := func(ctx *types.Context) error {
+		user, _ := ctx.Get("user")
+		ctx.Json(map[string]interface{}{"id": ctx.Param("id"), "user": user})
+		return nil
+	}
+
+	cleanapitest.NewHandlerClient(t, hf).
+		Get("/anything").
+		Param("id", "42").
+		WithValue("user", "alice").
+		Do().
+		ExpectNoError().
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON("", map[string]string{"id": "42", "user": "alice"})
+}
@@ -3,21 +3,52 @@ package server
 import (
 	"clean-rest-api/handlers"
 	cleanapi "clean-rest-api/pkg/clean-api"
-	"clean-rest-api/types"
-	"errors"
+	"clean-rest-api/pkg/clean-api/middleware"
+	"clean-rest-api/pkg/clean-api/openapi"
+	"clean-rest-api/repository"
+	"clean-rest-api/routes"
+	"context"
 	"log"
-	"net/http"
+	"os"
 )
 
+// Run starts the api. Messages are kept in memory unless STORAGE=sqlite,
+// in which case they are stored in SQLITE_PATH (default messages.db).
 func Run() {
 	c := cleanapi.New()
+	c.Use(middleware.Recover(), middleware.RequestID(), middleware.Logger())
 
-	c.Get("/", func(ctx *types.Context) error {
-		msgHandler := handlers.NewMessageHandler()
-		ctx.Json(msgHandler.GetAllMessages())
-		return errors.New("demo error")
+	repo, err := newMessageRepository(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	routes.RegisterRoutes(c, handlers.NewMessageHandler(repo))
+	c.ServeDocs(openapi.Info{Title: "clean-rest-api", Version: "1.0.0"})
+
+	if err := c.Listen(cleanapi.ListenOptions{Addr: ":8080"}); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("server stopped")
+}
+
+func newMessageRepository(c *cleanapi.CleanApi) (repository.MessageRepository, error) {
+	if os.Getenv("STORAGE") != "sqlite" {
+		return repository.NewInMemoryMessageRepository(), nil
+	}
+
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "messages.db"
+	}
+
+	db, err := repository.OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	c.OnShutdown(func(ctx context.Context) error {
+		return db.Close()
 	})
 
-	log.Println("server is running on port :8080")
-	log.Fatal(http.ListenAndServe(":8080", c.Router))
+	return repository.NewSQLiteMessageRepository(context.Background(), db)
 }
@@ -3,16 +3,30 @@ package types
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/gorilla/mux"
 )
 
 type Context struct {
 	Writer  http.ResponseWriter
 	Request *http.Request
+	values  map[string]interface{}
+}
+
+func NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	return &Context{
+		Writer:  NewResponseWriter(w),
+		Request: r,
+	}
 }
 
 func (c *Context) Json(message interface{}) {
+	c.JsonWithStatus(http.StatusOK, message)
+}
+
+func (c *Context) JsonWithStatus(status int, message interface{}) {
 	c.Writer.Header().Add("Content-Type", "application/json")
-	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.WriteHeader(status)
 
 	err := json.NewEncoder(c.Writer).Encode(message)
 
@@ -21,3 +35,48 @@ func (c *Context) Json(message interface{}) {
 		return
 	}
 }
+
+// Param returns the path variable registered under name, eg: {id}
+func (c *Context) Param(name string) string {
+	return mux.Vars(c.Request)[name]
+}
+
+// Set stores a value for the lifetime of the request
+func (c *Context) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+// ContextKey is the key type for request context values that Get falls back to
+type ContextKey string
+
+// Get returns a value stored with Set, or else a value stored in the request
+// context under ContextKey(key) (eg: by a net/http middleware)
+func (c *Context) Get(key string) (interface{}, bool) {
+	if value, ok := c.values[key]; ok {
+		return value, true
+	}
+	if c.Request == nil {
+		return nil, false
+	}
+	value := c.Request.Context().Value(ContextKey(key))
+	return value, value != nil
+}
+
+// Status returns the status code written so far
+func (c *Context) Status() int {
+	if rw, ok := c.Writer.(*ResponseWriter); ok {
+		return rw.Status()
+	}
+	return http.StatusOK
+}
+
+// Written reports whether the handler already started the response
+func (c *Context) Written() bool {
+	if rw, ok := c.Writer.(*ResponseWriter); ok {
+		return rw.Written()
+	}
+	return false
+}
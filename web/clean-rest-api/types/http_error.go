@@ -0,0 +1,37 @@
+package types
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HttpError is an error that carries the status code it should be answered with
+type HttpError struct {
+	Code    int         `json:"-"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+	Err     error       `json:"-"` // the cause, if any
+}
+
+func NewHttpError(code int, message string) *HttpError {
+	return &HttpError{Code: code, Message: message}
+}
+
+func (e *HttpError) Error() string {
+	return e.Message
+}
+
+func (e *HttpError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorStatus returns the status code an error should be answered with.
+// HttpErrors use their own code, everything else is a 500, and so are
+// HttpErrors without a valid code, which WriteHeader would panic on.
+func ErrorStatus(err error) int {
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) && httpErr.Code >= 100 && httpErr.Code <= 999 {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
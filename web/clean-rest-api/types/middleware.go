@@ -0,0 +1,6 @@
+package types
+
+// Middleware wraps a HttpHandlerFunc. Because the wrapped handler returns an
+// error, a middleware can run code both before and after the handler and
+// inspect (or replace) the error it returned.
+type Middleware func(HttpHandlerFunc) HttpHandlerFunc
@@ -0,0 +1,68 @@
+package types
+
+import "net/http"
+
+// ResponseWriter records the status code and whether anything was written,
+// so middlewares and the error handler know what already went out
+type ResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+	// sent is set once this writer passed a header or body on, written
+	// may be set earlier by a writer wrapping it
+	sent bool
+	// parent also records what is written through a writer wrapping it
+	parent *ResponseWriter
+}
+
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Track returns a writer writing to inner, a wrapper of w (eg: installed by
+// a net/http middleware), that records its status and writes in w as well:
+// the wrapper may buffer or drop what it is given
+func (w *ResponseWriter) Track(inner http.ResponseWriter) *ResponseWriter {
+	if rw, ok := inner.(*ResponseWriter); ok && rw == w {
+		return w
+	}
+	return &ResponseWriter{ResponseWriter: inner, status: http.StatusOK, parent: w}
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	w.record(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.sent = true
+	w.record(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *ResponseWriter) record(status int) {
+	for rw := w; rw != nil && !rw.written; rw = rw.parent {
+		rw.status = status
+		rw.written = true
+	}
+}
+
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *ResponseWriter) Written() bool {
+	return w.written
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"gorilla-mux-router/models"
+	"sync"
+)
+
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+
+	subscriberBuffer = 64
+)
+
+type Event struct {
+	ID   uint64
+	Type string
+	Todo models.Todo
+}
+
+// Broker fans todo changes out to subscribers and keeps the last events in a
+// bounded log, so clients that reconnect can resume where they stopped
+type Broker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	log         []Event // ring buffer of the last len(log) events
+	size        int
+	start       int
+	subscribers map[*Subscription]struct{}
+	listeners   []func(Event)
+	closed      bool
+}
+
+// Subscription receives events on C. C is closed when the subscriber is too
+// slow to keep up (it should reconnect and resume) or the broker is closed.
+type Subscription struct {
+	C      chan Event
+	broker *Broker
+}
+
+func NewBroker(logSize int) *Broker {
+	if logSize < 1 {
+		logSize = 1
+	}
+	return &Broker{
+		nextID:      1,
+		log:         make([]Event, logSize),
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+func (b *Broker) Publish(eventType string, todo models.Todo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	event := Event{ID: b.nextID, Type: eventType, Todo: todo}
+	b.nextID++
+
+	for _, listener := range b.listeners {
+		listener(event)
+	}
+
+	if b.size < len(b.log) {
+		b.log[(b.start+b.size)%len(b.log)] = event
+		b.size++
+	} else {
+		b.log[b.start] = event
+		b.start = (b.start + 1) % len(b.log)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.C <- event:
+		default:
+			// never block publishers on a slow client, drop it instead
+			delete(b.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
+
+// Listen registers a function called synchronously, in order, for every
+// published event (eg: to keep an index up to date). It must not block.
+func (b *Broker) Listen(listener func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.listeners = append(b.listeners, listener)
+}
+
+// Subscribe registers a subscriber. With resume set, the events published
+// after lastID are returned to be sent first; complete is false when some of
+// them already left the log (or lastID is unknown) and the client must reload.
+func (b *Broker) Subscribe(lastID uint64, resume bool) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{C: make(chan Event, subscriberBuffer), broker: b}
+	if b.closed {
+		close(sub.C)
+		return sub, nil, true
+	}
+	b.subscribers[sub] = struct{}{}
+
+	if !resume {
+		return sub, nil, true
+	}
+
+	// id of the oldest event still in the log
+	oldest := b.nextID
+	if b.size > 0 {
+		oldest = b.log[b.start].ID
+	}
+	complete = lastID < b.nextID && lastID+1 >= oldest
+
+	for i := 0; i < b.size; i++ {
+		event := b.log[(b.start+i)%len(b.log)]
+		if event.ID > lastID {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, complete
+}
+
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.C)
+	}
+}
+
+// Close ends every subscription, so streaming handlers return (eg: on shutdown)
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.C)
+	}
+}
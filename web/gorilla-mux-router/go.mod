@@ -2,4 +2,7 @@ module gorilla-mux-router
 
 go 1.23.4
 
-require github.com/gorilla/mux v1.8.1 // indirect
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/kljensen/snowball v0.10.0
+)
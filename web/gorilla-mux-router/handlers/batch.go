@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/store"
+	"gorilla-mux-router/utils"
+	"net/http"
+)
+
+const maxBatchSize = 100
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation creates, updates or deletes a single todo. Updates and
+// deletes must send the version they last saw, like If-Match does for
+// single requests.
+type BatchOperation struct {
+	Op      string       `json:"op"` // create, update or delete
+	Id      int          `json:"id,omitempty"`
+	Version int          `json:"version,omitempty"`
+	Todo    *models.Todo `json:"todo,omitempty"`
+}
+
+type BatchResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Todo   *models.Todo `json:"todo,omitempty"`
+	// Next is the todo created by completing a recurring one
+	Next  *models.Todo `json:"next,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// BatchTodos applies many operations in one request. Operations run in order
+// and independently: a failing one doesn't stop or undo the others, check
+// the status of every result.
+func (h *TodoHandler) BatchTodos(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSize {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("a batch needs between 1 and %v operations", maxBatchSize))
+		return
+	}
+
+	results := make([]BatchResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = h.applyOperation(op)
+		results[i].Index = i
+	}
+
+	utils.WriteJson(w, http.StatusOK, map[string][]BatchResult{"results": results})
+}
+
+func (h *TodoHandler) applyOperation(op BatchOperation) BatchResult {
+	switch op.Op {
+	case "create":
+		if op.Todo == nil {
+			return BatchResult{Status: http.StatusBadRequest, Error: "todo is required"}
+		}
+		if err := validateTodo(*op.Todo); err != nil {
+			return BatchResult{Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		todo, err := h.createTodo(*op.Todo)
+		if err != nil {
+			return batchError(err)
+		}
+		return BatchResult{Status: http.StatusCreated, Todo: &todo}
+
+	case "update":
+		if op.Todo == nil {
+			return BatchResult{Status: http.StatusBadRequest, Error: "todo is required"}
+		}
+		if err := validateTodo(*op.Todo); err != nil {
+			return BatchResult{Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		if op.Todo.Id != 0 && op.Todo.Id != op.Id {
+			return BatchResult{Status: http.StatusBadRequest, Error: "id of the todo does not match the id of the operation"}
+		}
+		if op.Version == 0 {
+			return BatchResult{Status: http.StatusPreconditionRequired, Error: "version is required"}
+		}
+		current, err := h.store.Get(op.Id)
+		if err != nil {
+			return batchError(err)
+		}
+		todo := *op.Todo
+		todo.Version = op.Version
+		todo, next, err := h.updateTodo(current, todo)
+		if err != nil {
+			return batchError(err)
+		}
+		return BatchResult{Status: http.StatusOK, Todo: &todo, Next: next}
+
+	case "delete":
+		if op.Version == 0 {
+			return BatchResult{Status: http.StatusPreconditionRequired, Error: "version is required"}
+		}
+		current, err := h.store.Get(op.Id)
+		if err != nil {
+			return batchError(err)
+		}
+		if current.Version != op.Version {
+			return batchError(store.ErrVersionMismatch)
+		}
+		// the event carries the deleted todo, as for a single DELETE
+		if err := h.deleteTodo(current); err != nil {
+			return batchError(err)
+		}
+		return BatchResult{Status: http.StatusNoContent}
+	}
+
+	return BatchResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("unknown operation %q", op.Op)}
+}
+
+func batchError(err error) BatchResult {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return BatchResult{Status: http.StatusNotFound, Error: err.Error()}
+	case errors.Is(err, store.ErrConflict):
+		return BatchResult{Status: http.StatusConflict, Error: err.Error()}
+	case errors.Is(err, store.ErrVersionMismatch):
+		return BatchResult{Status: http.StatusPreconditionFailed, Error: err.Error()}
+	}
+	return BatchResult{Status: http.StatusInternalServerError, Error: "internal server error"}
+}
@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/handlers"
+	"gorilla-mux-router/models"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "a"}, models.Todo{Title: "b"})
+
+	rec := s.do("POST", "/todos:batch", handlers.BatchRequest{Operations: []handlers.BatchOperation{
+		{Op: "create", Todo: &models.Todo{Title: "c"}},
+		{Op: "update", Id: 1, Version: 1, Todo: &models.Todo{Title: "a2"}},
+		{Op: "update", Id: 1, Version: 1, Todo: &models.Todo{Title: "stale"}},
+		{Op: "update", Id: 1, Version: 2, Todo: &models.Todo{Id: 2, Title: "other id"}},
+		{Op: "update", Id: 1, Todo: &models.Todo{Title: "no version"}},
+		{Op: "update", Id: 9, Version: 1, Todo: &models.Todo{Title: "missing"}},
+		{Op: "create", Todo: &models.Todo{}},
+		{Op: "delete", Id: 2, Version: 1},
+		{Op: "rename", Id: 2},
+	}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %v: %v", rec.Code, rec.Body.String())
+	}
+
+	var body struct{ Results []handlers.BatchResult }
+	decode(t, rec, &body)
+	want := []int{
+		http.StatusCreated,
+		http.StatusOK,
+		http.StatusPreconditionFailed,
+		http.StatusBadRequest,
+		http.StatusPreconditionRequired,
+		http.StatusNotFound,
+		http.StatusBadRequest,
+		http.StatusNoContent,
+		http.StatusBadRequest,
+	}
+	if len(body.Results) != len(want) {
+		t.Fatalf("results = %+v", body.Results)
+	}
+	for i, result := range body.Results {
+		if result.Index != i || result.Status != want[i] {
+			t.Errorf("result %v = %+v, want status %v", i, result, want[i])
+		}
+	}
+
+	// the failed operations changed nothing, the others were all applied
+	todos, _ := s.store.List()
+	if len(todos) != 2 || todos[0].Title != "a2" || todos[0].Version != 2 || todos[1].Title != "c" {
+		t.Errorf("todos = %+v", todos)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	s := newServer(t)
+
+	if rec := s.do("POST", "/todos:batch", handlers.BatchRequest{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: status %v", rec.Code)
+	}
+	ops := make([]handlers.BatchOperation, 101)
+	for i := range ops {
+		ops[i] = handlers.BatchOperation{Op: "create", Todo: &models.Todo{Title: "x"}}
+	}
+	if rec := s.do("POST", "/todos:batch", handlers.BatchRequest{Operations: ops}); rec.Code != http.StatusBadRequest {
+		t.Errorf("batch of %v: status %v", len(ops), rec.Code)
+	}
+}
+
+func TestBatchIdempotent(t *testing.T) {
+	s := newServer(t)
+	batch := handlers.BatchRequest{Operations: []handlers.BatchOperation{
+		{Op: "create", Todo: &models.Todo{Title: "once"}},
+	}}
+
+	first := s.do("POST", "/todos:batch", batch, "Idempotency-Key", "k")
+	retry := s.do("POST", "/todos:batch", batch, "Idempotency-Key", "k")
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry = %v %v", retry.Code, retry.Body.String())
+	}
+	if todos, _ := s.store.List(); len(todos) != 1 {
+		t.Errorf("todos = %+v", todos)
+	}
+}
+
+func TestBatchDeleteEvent(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "deleted", Body: "in a batch", Tags: []string{"home"}, Priority: 3})
+	stored, _ := s.store.Get(1)
+	sub, _, _ := s.broker.Subscribe(0, false)
+	defer sub.Close()
+
+	s.do("POST", "/todos:batch", handlers.BatchRequest{Operations: []handlers.BatchOperation{
+		{Op: "delete", Id: 1, Version: 1},
+	}})
+
+	// the same payload as a single DELETE: the whole deleted todo
+	event := <-sub.C
+	if event.Type != events.Deleted || !reflect.DeepEqual(event.Todo, stored) {
+		t.Errorf("event %v %+v, want the deleted todo %+v", event.Type, event.Todo, stored)
+	}
+}
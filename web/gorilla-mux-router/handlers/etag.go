@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag is the strong entity tag of a todo, derived from its version
+func etag(todo models.Todo) string {
+	return `"` + strconv.Itoa(todo.Version) + `"`
+}
+
+func setETag(w http.ResponseWriter, todo models.Todo) {
+	w.Header().Set("ETag", etag(todo))
+}
+
+// requireIfMatch answers with a 428 when the request has no If-Match header,
+// so clients can't overwrite a todo without saying which version they saw
+func requireIfMatch(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("If-Match") == "" {
+		utils.WriteError(w, http.StatusPreconditionRequired, "If-Match header is required, send the ETag of the todo")
+		return false
+	}
+	return true
+}
+
+// checkIfMatch answers with a 412 when no entity tag of the If-Match header
+// matches the current version of the todo
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current models.Todo) bool {
+	if !matchesETag(r.Header.Get("If-Match"), current) {
+		setETag(w, current)
+		utils.WriteError(w, http.StatusPreconditionFailed, "todo was modified, fetch it again and retry")
+		return false
+	}
+	return true
+}
+
+// matchesETag reports whether a list of entity tags (eg: `"1", "2"` or `*`)
+// matches the todo. Weak tags never match, If-Match uses strong comparison.
+func matchesETag(header string, todo models.Todo) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag(todo) {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified answers with a 304 when If-None-Match matches the todo
+func notModified(w http.ResponseWriter, r *http.Request, todo models.Todo) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		// If-None-Match uses weak comparison
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(todo) {
+			setETag(w, todo)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"gorilla-mux-router/models"
+	"net/http"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "a"})
+
+	rec := s.do("GET", "/todos/1", nil)
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("ETag = %q", etag)
+	}
+
+	rec = s.do("PUT", "/todos/1", models.Todo{Title: "b"}, "If-Match", `"1"`)
+	if etag := rec.Header().Get("ETag"); rec.Code != http.StatusOK || etag != `"2"` {
+		t.Errorf("put: status %v, ETag %q", rec.Code, etag)
+	}
+}
+
+func TestPreconditionRequired(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "a"})
+
+	for _, method := range []string{"PUT", "PATCH", "DELETE"} {
+		var body interface{}
+		if method != "DELETE" {
+			body = models.Todo{Title: "b"}
+		}
+		if rec := s.do(method, "/todos/1", body); rec.Code != http.StatusPreconditionRequired {
+			t.Errorf("%v without If-Match: status %v", method, rec.Code)
+		}
+	}
+
+	if todo, _ := s.store.Get(1); todo.Title != "a" || todo.Version != 1 {
+		t.Errorf("todo changed: %+v", todo)
+	}
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "a"})
+	s.do("PATCH", "/todos/1", map[string]string{"title": "b"}, "If-Match", `"1"`)
+
+	for _, ifMatch := range []string{`"1"`, `W/"2"`, `"3", "4"`} {
+		for _, method := range []string{"PUT", "PATCH", "DELETE"} {
+			var body interface{}
+			if method != "DELETE" {
+				body = models.Todo{Title: "c"}
+			}
+			rec := s.do(method, "/todos/1", body, "If-Match", ifMatch)
+			// the current ETag is sent back, so the client can fetch and retry
+			if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("ETag") != `"2"` {
+				t.Errorf("%v with If-Match %v: status %v, ETag %q", method, ifMatch, rec.Code, rec.Header().Get("ETag"))
+			}
+		}
+	}
+
+	// a list holding the current tag matches
+	if rec := s.do("PATCH", "/todos/1", map[string]string{"title": "c"}, "If-Match", `"1", "2"`); rec.Code != http.StatusOK {
+		t.Errorf("If-Match list: status %v", rec.Code)
+	}
+	if rec := s.do("DELETE", "/todos/1", nil, "If-Match", "*"); rec.Code != http.StatusNoContent {
+		t.Errorf("If-Match *: status %v", rec.Code)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "a"})
+
+	for ifNoneMatch, want := range map[string]int{
+		`"1"`:        http.StatusNotModified,
+		`W/"1"`:      http.StatusNotModified,
+		`"0", "1"`:   http.StatusNotModified,
+		"*":          http.StatusNotModified,
+		`"2"`:        http.StatusOK,
+		`"0", W/"3"`: http.StatusOK,
+	} {
+		rec := s.do("GET", "/todos/1", nil, "If-None-Match", ifNoneMatch)
+		if rec.Code != want || rec.Header().Get("ETag") != `"1"` {
+			t.Errorf("If-None-Match %v: status %v, ETag %q", ifNoneMatch, rec.Code, rec.Header().Get("ETag"))
+		}
+		if want == http.StatusNotModified && rec.Body.Len() > 0 {
+			t.Errorf("If-None-Match %v: body %q", ifNoneMatch, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+// TodoEvents streams todo changes as Server-Sent Events. Clients resume with
+// the Last-Event-ID header; when the events they missed are no longer in the
+// log, a "reset" event tells them to reload the list first.
+func (h *TodoHandler) TodoEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	var lastID uint64
+	header := r.Header.Get("Last-Event-ID")
+	resume := header != ""
+	if resume {
+		var err error
+		if lastID, err = strconv.ParseUint(header, 10, 64); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid Last-Event-ID header")
+			return
+		}
+	}
+
+	sub, backlog, complete := h.broker.Subscribe(lastID, resume)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range backlog {
+		writeEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// dropped for being too slow or shutting down, the client reconnects
+				return
+			}
+			writeEvent(w, event)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	data, _ := json.Marshal(event.Todo)
+	fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Type, data)
+}
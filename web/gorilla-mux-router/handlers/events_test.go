@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/search"
+	"gorilla-mux-router/store"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+var eventPattern = regexp.MustCompile(`(?m)^id: (\d+)\nevent: (\w+)\n`)
+
+// stream reads the events sent right after connecting, the request is
+// cancelled beforehand so the stream ends once the backlog is written
+func (s *server) stream(lastEventID string) (ids []string, types []string, body string) {
+	s.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/todos/events", nil).WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("status %v: %v", rec.Code, rec.Body.String())
+	}
+
+	for _, match := range eventPattern.FindAllStringSubmatch(rec.Body.String(), -1) {
+		ids = append(ids, match[1])
+		types = append(types, match[2])
+	}
+	return ids, types, rec.Body.String()
+}
+
+func TestEventsResume(t *testing.T) {
+	s := newServer(t)
+	s.do("POST", "/todos", models.Todo{Title: "a"})
+	s.do("POST", "/todos", models.Todo{Title: "b"})
+	s.do("PATCH", "/todos/1", map[string]bool{"completed": true}, "If-Match", `"1"`)
+	s.do("DELETE", "/todos/2", nil, "If-Match", `"1"`)
+
+	ids, types, _ := s.stream("2")
+	if !reflect.DeepEqual(ids, []string{"3", "4"}) || !reflect.DeepEqual(types, []string{"updated", "deleted"}) {
+		t.Errorf("events after 2 = %v %v", ids, types)
+	}
+
+	// a new client gets no backlog, an up to date one neither
+	if ids, _, _ := s.stream(""); len(ids) != 0 {
+		t.Errorf("events without Last-Event-ID = %v", ids)
+	}
+	if ids, _, body := s.stream("4"); len(ids) != 0 || regexp.MustCompile("event: reset").MatchString(body) {
+		t.Errorf("events after 4 = %v: %q", ids, body)
+	}
+}
+
+func TestEventsResumeTooOld(t *testing.T) {
+	s := newServer(t)
+	for i := 0; i < 105; i++ {
+		s.do("POST", "/todos", models.Todo{Title: fmt.Sprint("todo ", i)})
+	}
+
+	// the log keeps the last 100 events, the client must reload
+	ids, _, body := s.stream("1")
+	if !regexp.MustCompile(`^event: reset\n`).MatchString(body) || len(ids) != 100 || ids[0] != "6" {
+		t.Errorf("%v events, starting with %q", len(ids), body[:40])
+	}
+
+	if rec := s.do("GET", "/todos/events", nil, "Last-Event-ID", "nope"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID: status %v", rec.Code)
+	}
+}
+
+// slowStore pauses after saving an update, giving other writers a chance to
+// get between the update and its event
+type slowStore struct {
+	store.TodoStore
+}
+
+func (s slowStore) Update(id int, todo models.Todo) (models.Todo, error) {
+	todo, err := s.TodoStore.Update(id, todo)
+	time.Sleep(time.Millisecond)
+	return todo, err
+}
+
+// TestEventsOrder updates a todo from many goroutines, subscribers must see
+// the versions in the order the store saved them
+func TestEventsOrder(t *testing.T) {
+	s := newServerWithStore(t, slowStore{store.NewMemoryStore(models.Todo{Title: "shared"})})
+	sub, _, _ := s.broker.Subscribe(0, false)
+	defer sub.Close()
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := map[string]string{"title": fmt.Sprint("title-", i)}
+			if rec := s.do("PATCH", "/todos/1", body, "If-Match", "*"); rec.Code != http.StatusOK {
+				t.Errorf("status %v: %v", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	var last events.Event
+	for i := 0; i < writers; i++ {
+		event := <-sub.C
+		if event.Todo.Version != last.Todo.Version+1 && last.ID != 0 {
+			t.Errorf("event %v has version %v after version %v", event.ID, event.Todo.Version, last.Todo.Version)
+		}
+		last = event
+	}
+
+	// the last event is the stored todo, so is the index
+	stored, _ := s.store.Get(1)
+	if !reflect.DeepEqual(last.Todo, stored) {
+		t.Errorf("last event %+v, stored %+v", last.Todo, stored)
+	}
+	var results []search.Result
+	decode(t, s.do("GET", "/todos/search?q="+stored.Title, nil), &results)
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Todo, stored) {
+		t.Errorf("search %q = %+v", stored.Title, results)
+	}
+}
@@ -1,42 +1,297 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"gorilla-mux-router/events"
 	"gorilla-mux-router/models"
+	"gorilla-mux-router/query"
+	"gorilla-mux-router/search"
+	"gorilla-mux-router/store"
 	"gorilla-mux-router/utils"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
-var (
-	todos []models.Todo = []models.Todo{
-		{Id: 1, Title: "Pray", Body: "Pray 5 times everyday", Completed: true},
+type TodoHandler struct {
+	store  store.TodoStore
+	broker *events.Broker
+	index  *search.Index
+
+	// mu is held from a mutation of the store to the publication of its
+	// event, so events are published in the order the store applied them
+	mu sync.Mutex
+}
+
+// NewTodoHandler creates the handlers, every successful mutation is published
+// to broker. index must be kept in sync with the store (see search.Index.Apply).
+func NewTodoHandler(todoStore store.TodoStore, broker *events.Broker, index *search.Index) *TodoHandler {
+	return &TodoHandler{store: todoStore, broker: broker, index: index}
+}
+
+// createTodo saves a new todo and publishes it
+func (h *TodoHandler) createTodo(todo models.Todo) (models.Todo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	todo, err := h.store.Create(todo)
+	if err != nil {
+		return models.Todo{}, err
 	}
-)
+	h.broker.Publish(events.Created, todo)
+	return todo, nil
+}
 
-func GetAllTodos(w http.ResponseWriter, r *http.Request) {
-	var content []byte // the content to send as response
+// deleteTodo removes todo if its version is the stored one and publishes it
+func (h *TodoHandler) deleteTodo(todo models.Todo) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	// get the value of query completed eg: completed?=true
-	query := r.URL.Query()["completed"]
+	if err := h.store.Delete(todo.Id, todo.Version); err != nil {
+		return err
+	}
+	h.broker.Publish(events.Deleted, todo)
+	return nil
+}
+
+// TodoPatch holds the fields of a partial update, nil fields are left untouched
+type TodoPatch struct {
+	Title      *string    `json:"title"`
+	Body       *string    `json:"body"`
+	Completed  *bool      `json:"completed"`
+	Due        *time.Time `json:"due"`
+	Priority   *int       `json:"priority"`
+	Tags       *[]string  `json:"tags"`
+	Recurrence *string    `json:"recurrence"`
+}
+
+// GetAllTodos lists todos, see query.Query for the supported parameters.
+// The total number of matching todos is sent in the X-Total-Count header.
+func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
+	h.listTodos(w, r, nil)
+}
 
-	// if the num of queries is not 0, then send the filtered response
-	if len(query) != 0 {
-		isCompleted, _ := strconv.ParseBool(query[0])
-		completedTodos := []models.Todo{}
+// listTodos answers with the todos accepted by where (all of them if nil)
+// filtered, sorted and paginated by the query
+func (h *TodoHandler) listTodos(w http.ResponseWriter, r *http.Request, where func(models.Todo) bool) {
+	q, err := query.Parse(r.URL.Query())
+	if err != nil {
+		var queryErr *query.Error
+		if errors.As(err, &queryErr) {
+			utils.WriteJson(w, http.StatusBadRequest, map[string]interface{}{
+				"error":         queryErr.Error(),
+				"invalidParams": queryErr.Params,
+			})
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	todos, err := h.store.List()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "failed to fetch todos")
+		return
+	}
+	if where != nil {
+		selected := []models.Todo{}
 		for _, todo := range todos {
-			if todo.Completed == isCompleted {
-				completedTodos = append(completedTodos, todo)
+			if where(todo) {
+				selected = append(selected, todo)
 			}
-			content, _ = utils.Json(completedTodos)
 		}
-	} else {
-		content, _ = utils.Json(todos)
+		todos = selected
+	}
+
+	page := q.Apply(todos)
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if link := q.LinkHeader(r.URL, page); link != "" {
+		w.Header().Set("Link", link)
+	}
+	utils.WriteJson(w, http.StatusOK, page.Todos)
+}
+
+func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := todoID(w, r)
+	if !ok {
+		return
+	}
+
+	todo, err := h.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if notModified(w, r, todo) {
+		return
+	}
+
+	setETag(w, todo)
+	utils.WriteJson(w, http.StatusOK, todo)
+}
+
+func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
+	var todo models.Todo
+	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateTodo(todo); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	todo, err := h.createTodo(todo)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/todos/"+strconv.Itoa(todo.Id))
+	setETag(w, todo)
+	utils.WriteJson(w, http.StatusCreated, todo)
+}
+
+// UpdateTodo replaces a todo with the request body, the If-Match header must
+// hold the current ETag of the todo
+func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := todoID(w, r)
+	if !ok || !requireIfMatch(w, r) {
+		return
+	}
+
+	var todo models.Todo
+	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateTodo(todo); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if todo.Id != 0 && todo.Id != id {
+		utils.WriteError(w, http.StatusBadRequest, "id in body does not match the url")
+		return
+	}
+
+	current, err := h.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
+	// the store rejects the update if the todo changed since the check
+	todo.Version = current.Version
+	todo, next, err := h.updateTodo(current, todo)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	setNextOccurrence(w, next)
+	setETag(w, todo)
+	utils.WriteJson(w, http.StatusOK, todo)
+}
+
+// PatchTodo applies a partial update, see patchedTodo for the supported
+// formats. The If-Match header must hold the current ETag of the todo, or *
+// to patch whatever version is current.
+func (h *TodoHandler) PatchTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := todoID(w, r)
+	if !ok || !requireIfMatch(w, r) {
+		return
+	}
+	p, ok := readPatch(w, r)
+	if !ok {
+		return
+	}
+
+	for {
+		current, err := h.store.Get(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if !checkIfMatch(w, r, current) {
+			return
+		}
+
+		todo, ok := patchedTodo(w, p, current)
+		if !ok {
+			return
+		}
+
+		// the patch was applied to a copy, the store only saves it if the
+		// todo did not change in the meantime
+		todo, next, err := h.updateTodo(current, todo)
+		if errors.Is(err, store.ErrVersionMismatch) && strings.TrimSpace(r.Header.Get("If-Match")) == "*" {
+			// any version matches *, apply the patch to the new one
+			continue
+		}
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+
+		setNextOccurrence(w, next)
+		setETag(w, todo)
+		utils.WriteJson(w, http.StatusOK, todo)
+		return
+	}
+}
+
+// DeleteTodo removes a todo, the If-Match header must hold its current ETag
+func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := todoID(w, r)
+	if !ok || !requireIfMatch(w, r) {
+		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.Write(content)
+	current, err := h.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
+	if err := h.deleteTodo(current); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func GetTodo(w http.ResponseWriter, r *http.Request) {
+// todoID reads the {id} path variable, answering with a 400 if it is not a number
+func todoID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid todo id")
+		return 0, false
+	}
+	return id, true
+}
 
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, store.ErrConflict):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, store.ErrVersionMismatch):
+		utils.WriteError(w, http.StatusPreconditionFailed, err.Error())
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
 }
@@ -0,0 +1,295 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/routes"
+	"gorilla-mux-router/search"
+	"gorilla-mux-router/store"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type server struct {
+	t       *testing.T
+	handler http.Handler
+	store   store.TodoStore
+	broker  *events.Broker
+}
+
+func newServer(t *testing.T, todos ...models.Todo) *server {
+	return newServerWithStore(t, store.NewMemoryStore(todos...))
+}
+
+func newServerWithStore(t *testing.T, todoStore store.TodoStore) *server {
+	broker := events.NewBroker(100)
+	index := search.NewIndex()
+	broker.Listen(index.Apply)
+	todos, _ := todoStore.List()
+	for _, todo := range todos {
+		index.Put(todo)
+	}
+
+	return &server{
+		t:     t,
+		store: todoStore,
+		handler: routes.RegisterRoutes(routes.Config{
+			Store:             todoStore,
+			Broker:            broker,
+			Index:             index,
+			IdempotencyWindow: time.Minute,
+		}),
+		broker: broker,
+	}
+}
+
+// do sends a request with a json body (unless body is a string) and the
+// given headers as name, value pairs
+func (s *server) do(method string, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var content string
+	switch b := body.(type) {
+	case nil:
+	case string:
+		content = b
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		content = string(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(content))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("cannot decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestTodoCRUD(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("POST", "/todos", models.Todo{Title: "write tests"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/todos/1" {
+		t.Fatalf("create: status %v, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = s.do("POST", "/todos", models.Todo{Id: 1, Title: "duplicate"})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("create with a taken id: status %v", rec.Code)
+	}
+	rec = s.do("POST", "/todos", models.Todo{})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("create without a title: status %v", rec.Code)
+	}
+
+	var todo models.Todo
+	rec = s.do("GET", "/todos/1", nil)
+	decode(t, rec, &todo)
+	if rec.Code != http.StatusOK || todo.Title != "write tests" || todo.Version != 1 {
+		t.Errorf("get: status %v, todo %+v", rec.Code, todo)
+	}
+
+	rec = s.do("PUT", "/todos/1", models.Todo{Title: "write more tests"}, "If-Match", `"1"`)
+	decode(t, rec, &todo)
+	if rec.Code != http.StatusOK || todo.Title != "write more tests" || todo.Version != 2 {
+		t.Errorf("put: status %v, todo %+v", rec.Code, todo)
+	}
+	rec = s.do("PUT", "/todos/1", models.Todo{Id: 2, Title: "other id"}, "If-Match", `"2"`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("put with another id in the body: status %v", rec.Code)
+	}
+
+	rec = s.do("PATCH", "/todos/1", map[string]bool{"completed": true}, "If-Match", `"2"`)
+	decode(t, rec, &todo)
+	if rec.Code != http.StatusOK || !todo.Completed || todo.Title != "write more tests" {
+		t.Errorf("patch: status %v, todo %+v", rec.Code, todo)
+	}
+
+	rec = s.do("DELETE", "/todos/1", nil, "If-Match", `"3"`)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("delete: status %v", rec.Code)
+	}
+
+	for _, method := range []string{"GET", "PUT", "PATCH", "DELETE"} {
+		var body interface{}
+		if method == "PUT" || method == "PATCH" {
+			body = models.Todo{Title: "gone"}
+		}
+		if rec := s.do(method, "/todos/1", body, "If-Match", "*"); rec.Code != http.StatusNotFound {
+			t.Errorf("%v of a deleted todo: status %v", method, rec.Code)
+		}
+	}
+}
+
+func TestPatchFormats(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "patched"})
+
+	tests := []struct {
+		contentType string
+		body        string
+		title       string
+	}{
+		{"application/json", `{"title": "todo patch"}`, "todo patch"},
+		{"application/merge-patch+json", `{"title": "merge patch", "body": null}`, "merge patch"},
+		{"application/json-patch+json; charset=utf-8", `[{"op": "replace", "path": "/title", "value": "json patch"}]`, "json patch"},
+	}
+	for n, test := range tests {
+		var todo models.Todo
+		rec := s.do("PATCH", "/todos/1", test.body, "Content-Type", test.contentType, "If-Match", fmt.Sprintf(`"%v"`, n+1))
+		decode(t, rec, &todo)
+		if rec.Code != http.StatusOK || todo.Title != test.title {
+			t.Errorf("%v: status %v, todo %+v", test.contentType, rec.Code, todo)
+		}
+	}
+
+	// the format is checked first, whatever the todo and its version
+	for _, path := range []string{"/todos/1", "/todos/9"} {
+		rec := s.do("PATCH", path, "<title/>", "Content-Type", "application/xml", "If-Match", `"1"`)
+		if rec.Code != http.StatusUnsupportedMediaType || rec.Header().Get("Accept-Patch") == "" {
+			t.Errorf("%v: status %v, Accept-Patch %q", path, rec.Code, rec.Header().Get("Accept-Patch"))
+		}
+	}
+}
+
+// patchTags adds tag to the todo with a JSON Patch, fetching it again when
+// another patch won the race
+func patchTags(s *server, tag string) error {
+	for attempt := 0; attempt < 100; attempt++ {
+		current, err := s.store.Get(1)
+		if err != nil {
+			return err
+		}
+		body := fmt.Sprintf(`[{"op": "add", "path": "/tags/-", "value": %q}]`, tag)
+		req := httptest.NewRequest("PATCH", "/todos/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		req.Header.Set("If-Match", fmt.Sprintf(`"%v"`, current.Version))
+
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		switch rec.Code {
+		case http.StatusOK:
+			return nil
+		case http.StatusPreconditionFailed:
+			continue
+		default:
+			return fmt.Errorf("status %v: %v", rec.Code, rec.Body.String())
+		}
+	}
+	return fmt.Errorf("%v: too many attempts", tag)
+}
+
+func TestConcurrentPatches(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "shared", Tags: []string{"seed"}})
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := patchTags(s, fmt.Sprint("tag-", i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every patch is kept, none overwrote another
+	todo, _ := s.store.Get(1)
+	if len(todo.Tags) != writers+1 || todo.Version != writers+1 {
+		t.Errorf("tags = %v, version %v", todo.Tags, todo.Version)
+	}
+}
+
+func TestConcurrentPatchesSameVersion(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "shared"})
+
+	const writers = 20
+	var wg sync.WaitGroup
+	statuses := make([]int, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := map[string]string{"title": fmt.Sprint("title-", i)}
+			statuses[i] = s.do("PATCH", "/todos/1", body, "If-Match", `"1"`).Code
+		}()
+	}
+	wg.Wait()
+
+	// all of them patched version 1, only the first one may win
+	sort.Ints(statuses)
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusPreconditionFailed || statuses[writers-1] != http.StatusPreconditionFailed {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
+
+func TestConcurrentPatchesAnyVersion(t *testing.T) {
+	s := newServer(t, models.Todo{Title: "shared", Tags: []string{"seed"}})
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := fmt.Sprintf(`[{"op": "add", "path": "/tags/-", "value": "tag-%v"}]`, i)
+			req := httptest.NewRequest("PATCH", "/todos/1", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json-patch+json")
+			req.Header.Set("If-Match", "*")
+			rec := httptest.NewRecorder()
+			s.handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("status %v: %v", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// * matches any version, the patch is applied again to the newest one
+	todo, _ := s.store.Get(1)
+	if len(todo.Tags) != writers+1 {
+		t.Errorf("tags = %v", todo.Tags)
+	}
+}
+
+func TestSearchRecreatedTodo(t *testing.T) {
+	s := newServer(t)
+	s.do("POST", "/todos", models.Todo{Title: "original"})
+	s.do("PATCH", "/todos/1", map[string]string{"title": "original again"}, "If-Match", `"1"`)
+	s.do("DELETE", "/todos/1", nil, "If-Match", `"2"`)
+
+	// the id of the deleted todo is taken again, with version 1
+	if rec := s.do("POST", "/todos", models.Todo{Id: 1, Title: "recreated"}); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %v", rec.Code)
+	}
+
+	var results []search.Result
+	decode(t, s.do("GET", "/todos/search?q=recreated", nil), &results)
+	if len(results) != 1 || results[0].Todo.Id != 1 {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/recurrence"
+	"gorilla-mux-router/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const icalTimeLayout = "20060102T150405Z"
+
+// ExportCalendar sends the todos having a due date as an iCalendar
+// (RFC 5545) file of VTODO components, to subscribe from a calendar app
+func (h *TodoHandler) ExportCalendar(w http.ResponseWriter, r *http.Request) {
+	todos, err := h.store.List()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "failed to fetch todos")
+		return
+	}
+
+	var b strings.Builder
+	writeLine := func(line string) { b.WriteString(foldLine(line)) }
+
+	stamp := time.Now().UTC().Format(icalTimeLayout)
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//gorilla-mux-router//todos//EN")
+	for _, todo := range todos {
+		if todo.Due == nil {
+			continue
+		}
+		writeLine("BEGIN:VTODO")
+		writeLine(fmt.Sprintf("UID:todo-%v@gorilla-mux-router", todo.Id))
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("SUMMARY:" + escapeText(todo.Title))
+		if todo.Body != "" {
+			writeLine("DESCRIPTION:" + escapeText(todo.Body))
+		}
+		writeLine("DUE:" + todo.Due.UTC().Format(icalTimeLayout))
+		if todo.Priority != 0 {
+			writeLine(fmt.Sprintf("PRIORITY:%v", todo.Priority))
+		}
+		if len(todo.Tags) > 0 {
+			tags := make([]string, len(todo.Tags))
+			for i, tag := range todo.Tags {
+				tags[i] = escapeText(tag)
+			}
+			writeLine("CATEGORIES:" + strings.Join(tags, ","))
+		}
+		if todo.Recurrence != "" {
+			rule, err := recurrence.Parse(todo.Recurrence)
+			if err != nil {
+				// validateTodo keeps invalid rules out, the store holds one anyway
+				log.Printf("todo %v exported without its invalid recurrence %q: %v", todo.Id, todo.Recurrence, err)
+			} else {
+				// the occurrences of a rule are counted from DTSTART, which the due date is
+				writeLine("DTSTART:" + todo.Due.UTC().Format(icalTimeLayout))
+				writeLine("RRULE:" + rule.String())
+			}
+		}
+		writeLine("STATUS:" + icalStatus(todo))
+		writeLine(fmt.Sprintf("SEQUENCE:%v", todo.Version-1))
+		writeLine("END:VTODO")
+	}
+	writeLine("END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.ics"`)
+	w.Write([]byte(b.String()))
+}
+
+func icalStatus(todo models.Todo) string {
+	if todo.Completed {
+		return "COMPLETED"
+	}
+	return "NEEDS-ACTION"
+}
+
+// escapeText escapes a TEXT value as required by RFC 5545 section 3.3.11
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine ends a content line with CRLF, splitting it in lines of at most
+// 75 octets continued by a space, without breaking utf-8 sequences
+func foldLine(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the leading space counts in the length of the continuation
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
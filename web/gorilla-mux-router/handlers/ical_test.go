@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"bytes"
+	"gorilla-mux-router/models"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCalendar(t *testing.T) {
+	due := time.Date(2026, time.March, 2, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	s := newServer(t,
+		models.Todo{Title: "Weekly review", Due: &due, Priority: 1, Recurrence: "freq=weekly;byday=mo;"},
+		models.Todo{Title: "Taxes, finally; no excuses", Body: "forms\nreceipts", Due: &due, Tags: []string{"home", "money"}, Completed: true},
+		models.Todo{Title: "No due date"},
+	)
+
+	rec := s.do("GET", "/todos.ics", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Fatalf("status %v, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if strings.Count(body, "BEGIN:VTODO") != 2 || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("body = %q", body)
+	}
+
+	todos := strings.Split(body, "BEGIN:VTODO\r\n")[1:]
+	for _, line := range []string{
+		"UID:todo-1@gorilla-mux-router",
+		"DUE:20260302T083000Z",
+		// recurring todos start on their due date, the rule is normalised
+		"DTSTART:20260302T083000Z",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		"PRIORITY:1",
+		"STATUS:NEEDS-ACTION",
+		"SEQUENCE:0",
+	} {
+		if !strings.Contains(todos[0], line+"\r\n") {
+			t.Errorf("todo 1 has no %q line: %q", line, todos[0])
+		}
+	}
+	for _, line := range []string{
+		`SUMMARY:Taxes\, finally\; no excuses`,
+		`DESCRIPTION:forms\nreceipts`,
+		"CATEGORIES:home,money",
+		"STATUS:COMPLETED",
+	} {
+		if !strings.Contains(todos[1], line+"\r\n") {
+			t.Errorf("todo 2 has no %q line: %q", line, todos[1])
+		}
+	}
+	for _, prefix := range []string{"DTSTART", "RRULE", "PRIORITY"} {
+		if strings.Contains(todos[1], prefix) {
+			t.Errorf("todo 2 has a %v: %q", prefix, todos[1])
+		}
+	}
+}
+
+func TestExportCalendarFolding(t *testing.T) {
+	due := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	s := newServer(t, models.Todo{Title: strings.Repeat("é", 100), Due: &due})
+
+	body := s.do("GET", "/todos.ics", nil).Body.String()
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %v octets: %q", len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(body, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("é", 100)+"\r\n") {
+		t.Errorf("unfolded = %q", unfolded)
+	}
+}
+
+func TestExportCalendarInvalidRecurrence(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	// the store doesn't validate, a rule could come from an older version
+	due := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	s := newServer(t, models.Todo{Title: "broken", Due: &due, Recurrence: "FREQ=HOURLY"})
+
+	body := s.do("GET", "/todos.ics", nil).Body.String()
+	if !strings.Contains(body, "SUMMARY:broken\r\n") || strings.Contains(body, "RRULE") || strings.Contains(body, "DTSTART") {
+		t.Errorf("body = %q", body)
+	}
+	if !strings.Contains(logs.String(), `invalid recurrence "FREQ=HOURLY"`) {
+		t.Errorf("logs = %q", logs.String())
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/patch"
+	"gorilla-mux-router/utils"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	jsonPatchType  = "application/json-patch+json"
+	mergePatchType = "application/merge-patch+json"
+
+	maxPatchSize = 1 << 20
+)
+
+// todoPatch is the body of a PATCH request, read once so it can be applied
+// again when the todo changed concurrently
+type todoPatch struct {
+	mediaType string
+	body      []byte
+}
+
+// readPatch reads the patch of the request, see patchedTodo for the supported
+// formats. It answers the request itself on failure.
+func readPatch(w http.ResponseWriter, r *http.Request) (todoPatch, bool) {
+	mediaType := "application/json"
+	if header := r.Header.Get("Content-Type"); header != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(header); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid Content-Type header")
+			return todoPatch{}, false
+		}
+	}
+
+	switch mediaType {
+	case "application/json", jsonPatchType, mergePatchType:
+	default:
+		w.Header().Set("Accept-Patch", fmt.Sprintf("%v, %v, application/json", jsonPatchType, mergePatchType))
+		utils.WriteError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported patch format %q", mediaType))
+		return todoPatch{}, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, "patch is too large")
+		return todoPatch{}, false
+	}
+	return todoPatch{mediaType: mediaType, body: body}, true
+}
+
+// patchedTodo applies the patch to current according to its content type
+// and validates the result. It answers the request itself on failure.
+//   - application/json-patch+json: a JSON Patch (RFC 6902)
+//   - application/merge-patch+json: a JSON Merge Patch (RFC 7396)
+//   - application/json: a TodoPatch, only the given fields are updated
+func patchedTodo(w http.ResponseWriter, p todoPatch, current models.Todo) (models.Todo, bool) {
+	var todo models.Todo
+	var err error
+	if p.mediaType == "application/json" {
+		todo, err = applyTodoPatch(current, p.body)
+	} else {
+		todo, err = applyDocumentPatch(current, p.body, p.mediaType)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, patch.ErrInvalidPatch):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return models.Todo{}, false
+	case errors.Is(err, patch.ErrTestFailed):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return models.Todo{}, false
+	default:
+		utils.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return models.Todo{}, false
+	}
+
+	if err := validateTodo(todo); err != nil {
+		utils.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return models.Todo{}, false
+	}
+	return todo, true
+}
+
+func applyTodoPatch(current models.Todo, body []byte) (models.Todo, error) {
+	var p TodoPatch
+	if err := json.Unmarshal(body, &p); err != nil {
+		return models.Todo{}, fmt.Errorf("%w: %v", patch.ErrInvalidPatch, err)
+	}
+
+	todo := current.Clone()
+	if p.Title != nil {
+		todo.Title = *p.Title
+	}
+	if p.Body != nil {
+		todo.Body = *p.Body
+	}
+	if p.Completed != nil {
+		todo.Completed = *p.Completed
+	}
+	if p.Due != nil {
+		todo.Due = p.Due
+	}
+	if p.Priority != nil {
+		todo.Priority = *p.Priority
+	}
+	if p.Tags != nil {
+		todo.Tags = *p.Tags
+	}
+	if p.Recurrence != nil {
+		todo.Recurrence = *p.Recurrence
+	}
+	return todo, nil
+}
+
+// applyDocumentPatch patches the json representation of the todo, the
+// result must still be a valid todo with the same id and version
+func applyDocumentPatch(current models.Todo, body []byte, mediaType string) (models.Todo, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return models.Todo{}, err
+	}
+
+	var patched []byte
+	if mediaType == jsonPatchType {
+		patched, err = patch.ApplyJSONPatch(doc, body)
+	} else {
+		patched, err = patch.ApplyMergePatch(doc, body)
+	}
+	if err != nil {
+		return models.Todo{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	var todo models.Todo
+	if err := decoder.Decode(&todo); err != nil {
+		return models.Todo{}, fmt.Errorf("patched todo is invalid: %v", err)
+	}
+	if todo.Id != current.Id || todo.Version != current.Version {
+		return models.Todo{}, errors.New("id and version can't be patched")
+	}
+	return todo, nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
+	"gorilla-mux-router/recurrence"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// validateTodo checks the fields a client can set
+func validateTodo(todo models.Todo) error {
+	if todo.Title == "" {
+		return errors.New("title is required")
+	}
+	if todo.Priority < 0 || todo.Priority > 9 {
+		return errors.New("priority must be between 0 (none) and 9")
+	}
+	for _, tag := range todo.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("tags can't be empty")
+		}
+	}
+	if todo.Recurrence != "" {
+		if todo.Due == nil {
+			return errors.New("a recurring todo needs a due date")
+		}
+		if _, err := recurrence.Parse(todo.Recurrence); err != nil {
+			return fmt.Errorf("invalid recurrence: %v", err)
+		}
+	}
+	return nil
+}
+
+// updateTodo saves todo over current. Completing a recurring todo moves its
+// recurrence to a new todo for the next occurrence, which is returned too.
+func (h *TodoHandler) updateTodo(current models.Todo, todo models.Todo) (models.Todo, *models.Todo, error) {
+	var next *models.Todo
+	if todo.Completed && !current.Completed && todo.Recurrence != "" {
+		next = nextOccurrence(todo)
+		todo.Recurrence = ""
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	todo, err := h.store.Update(current.Id, todo)
+	if err != nil {
+		return models.Todo{}, nil, err
+	}
+	h.broker.Publish(events.Updated, todo)
+
+	if next == nil {
+		return todo, nil, nil
+	}
+	created, err := h.store.Create(*next)
+	if err != nil {
+		// the completion is saved, only the series is lost
+		log.Printf("failed to create the next occurrence of todo %v: %v", todo.Id, err)
+		return todo, nil, nil
+	}
+	h.broker.Publish(events.Created, created)
+	return todo, &created, nil
+}
+
+// nextOccurrence returns the todo following a recurring one, nil when its
+// series is over
+func nextOccurrence(todo models.Todo) *models.Todo {
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil || todo.Due == nil {
+		return nil
+	}
+	due, rest, ok := rule.Next(*todo.Due)
+	if !ok {
+		return nil
+	}
+
+	next := todo.Clone()
+	next.Id = 0
+	next.Completed = false
+	next.Due = &due
+	next.Recurrence = rest.String()
+	return &next
+}
+
+// setNextOccurrence links the todo created by completing a recurring one
+func setNextOccurrence(w http.ResponseWriter, next *models.Todo) {
+	if next != nil {
+		w.Header().Set("Link", fmt.Sprintf(`</todos/%v>; rel="next-occurrence"`, next.Id))
+	}
+}
+
+// GetOverdueTodos lists the todos not completed before their due date, it
+// supports the same parameters as GetAllTodos
+func (h *TodoHandler) GetOverdueTodos(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	h.listTodos(w, r, func(todo models.Todo) bool {
+		return !todo.Completed && todo.Due != nil && todo.Due.Before(now)
+	})
+}
+
+// GetTodosDueThisWeek lists the todos due from monday to sunday of the
+// current week, in the time zone of the server
+func (h *TodoHandler) GetTodosDueThisWeek(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(int(now.Weekday())+6)%7, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, 7)
+	h.listTodos(w, r, func(todo models.Todo) bool {
+		return todo.Due != nil && !todo.Due.Before(start) && todo.Due.Before(end)
+	})
+}
+
+// GetTodosByTag lists the todos having the {tag} path variable, ignoring case
+func (h *TodoHandler) GetTodosByTag(w http.ResponseWriter, r *http.Request) {
+	tag := mux.Vars(r)["tag"]
+	h.listTodos(w, r, func(todo models.Todo) bool {
+		for _, t := range todo.Tags {
+			if strings.EqualFold(t, tag) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"gorilla-mux-router/utils"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchTodos runs a full-text search over titles and bodies, eg:
+// /todos/search?q=pray+mor&limit=10. Results are ranked by relevance and come
+// with html snippets where the matching words are wrapped in <mark>.
+func (h *TodoHandler) SearchTodos(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		utils.WriteError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			utils.WriteError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	utils.WriteJson(w, http.StatusOK, h.index.Search(q, limit))
+}
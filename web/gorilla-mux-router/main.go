@@ -1,16 +1,91 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
 	"gorilla-mux-router/routes"
+	"gorilla-mux-router/search"
+	"gorilla-mux-router/store"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 const PORT int = 8080
 
 func main() {
-	router := routes.RegisterRoutes()
-	log.Printf("server is listening on :%v", PORT)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", PORT), router))
+	dataDir := flag.String("data", "data", "directory where todos are persisted")
+	syncPolicy := flag.String("sync", "always", "when to fsync the todo log: always, interval or never")
+	eventLogSize := flag.Int("event-log", 1000, "number of todo events kept for clients resuming with Last-Event-ID")
+	idempotencyWindow := flag.Duration("idempotency-window", 24*time.Hour, "how long responses are kept for retries with the same Idempotency-Key")
+	flag.Parse()
+
+	policy, err := store.ParseSyncPolicy(*syncPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	todoStore, err := store.NewFileStore(store.FileStoreOptions{Dir: *dataDir, Sync: policy})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// seed the demo todo on the first run
+	if todos, _ := todoStore.List(); len(todos) == 0 {
+		todoStore.Create(models.Todo{Title: "Pray", Body: "Pray 5 times everyday", Completed: true})
+	}
+
+	// the index listens before being loaded from the store, so no change is
+	// missed in between. The todos are loaded as updates, which Apply ignores
+	// when it already has their version.
+	index := search.NewIndex()
+	broker := events.NewBroker(*eventLogSize)
+	broker.Listen(index.Apply)
+
+	todos, err := todoStore.List()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, todo := range todos {
+		index.Apply(events.Event{Type: events.Updated, Todo: todo})
+	}
+
+	server := &http.Server{
+		Addr: fmt.Sprintf(":%v", PORT),
+		Handler: routes.RegisterRoutes(routes.Config{
+			Store:             todoStore,
+			Broker:            broker,
+			Index:             index,
+			IdempotencyWindow: *idempotencyWindow,
+		}),
+	}
+	// Shutdown doesn't wait for event streams to end by themselves
+	server.RegisterOnShutdown(broker.Close)
+
+	go func() {
+		log.Printf("server is listening on :%v", PORT)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// close the store on shutdown so the log is compacted and synced
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println(err)
+	}
+	if err := todoStore.Close(); err != nil {
+		log.Fatal(err)
+	}
 }
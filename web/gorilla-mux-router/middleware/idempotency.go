@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"gorilla-mux-router/utils"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotentBody    = 1 << 20
+)
+
+// Idempotency replays the stored response of a request when it is retried
+// with the same Idempotency-Key header within the window, so retries over a
+// flaky connection don't create duplicates. Requests without the header are
+// passed through untouched.
+type Idempotency struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	responses map[string]*storedResponse
+	lastSweep time.Time
+}
+
+type storedResponse struct {
+	fingerprint [32]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+func NewIdempotency(window time.Duration) *Idempotency {
+	return &Idempotency{window: window, responses: map[string]*storedResponse{}}
+}
+
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// the same key must not be reused for a different request
+		fingerprint := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+		key = r.Method + " " + r.URL.Path + " " + key
+
+		i.mu.Lock()
+		i.sweep()
+		stored, ok := i.responses[key]
+		if ok && stored.done && time.Now().After(stored.expires) {
+			// expired but not swept yet
+			ok = false
+		}
+		switch {
+		case ok && stored.fingerprint != fingerprint:
+			i.mu.Unlock()
+			utils.WriteError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			return
+		case ok && !stored.done:
+			i.mu.Unlock()
+			utils.WriteError(w, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+			return
+		case ok:
+			i.mu.Unlock()
+			replay(w, stored)
+			return
+		}
+		i.responses[key] = &storedResponse{fingerprint: fingerprint, expires: time.Now().Add(i.window)}
+		i.mu.Unlock()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				// the handler panicked, the key would stay in flight forever
+				i.mu.Lock()
+				delete(i.responses, key)
+				i.mu.Unlock()
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+		completed = true
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if recorder.status >= 500 {
+			// server errors are not final, let the client retry
+			delete(i.responses, key)
+			return
+		}
+		i.responses[key] = &storedResponse{
+			fingerprint: fingerprint,
+			done:        true,
+			status:      recorder.status,
+			header:      w.Header().Clone(),
+			body:        recorder.body.Bytes(),
+			expires:     time.Now().Add(i.window),
+		}
+	})
+}
+
+func replay(w http.ResponseWriter, stored *storedResponse) {
+	for name, values := range stored.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.status)
+	w.Write(stored.body)
+}
+
+// sweep drops expired responses at most once a minute, must be called with mu held
+func (i *Idempotency) sweep() {
+	now := time.Now()
+	if now.Sub(i.lastSweep) < time.Minute {
+		return
+	}
+	i.lastSweep = now
+
+	for key, stored := range i.responses {
+		if stored.done && now.After(stored.expires) {
+			delete(i.responses, key)
+		}
+	}
+}
+
+// responseRecorder writes through to the client while keeping a copy of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter answers with the number of requests it handled
+type counter struct {
+	calls  atomic.Int32
+	status int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := c.calls.Add(1)
+	w.Header().Set("X-Call", fmt.Sprint(n))
+	w.WriteHeader(c.status)
+	fmt.Fprintf(w, "call %v", n)
+}
+
+func send(h http.Handler, path string, body string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	next := &counter{status: http.StatusCreated}
+	h := NewIdempotency(time.Minute).Middleware(next)
+
+	first := send(h, "/todos", `{"title":"a"}`, "key-1")
+	retry := send(h, "/todos", `{"title":"a"}`, "key-1")
+	if next.calls.Load() != 1 {
+		t.Fatalf("handler called %v times", next.calls.Load())
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("X-Call") != "1" {
+		t.Errorf("retry: status %v, body %q", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("only the replay is marked as replayed")
+	}
+
+	// other keys, paths and requests without a key are not replayed
+	send(h, "/todos", `{"title":"a"}`, "key-2")
+	send(h, "/other", `{"title":"a"}`, "key-1")
+	send(h, "/todos", `{"title":"a"}`, "")
+	if next.calls.Load() != 4 {
+		t.Errorf("handler called %v times", next.calls.Load())
+	}
+}
+
+func TestIdempotencyDifferentRequest(t *testing.T) {
+	next := &counter{status: http.StatusCreated}
+	h := NewIdempotency(time.Minute).Middleware(next)
+
+	send(h, "/todos", `{"title":"a"}`, "key")
+	if rec := send(h, "/todos", `{"title":"b"}`, "key"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status %v", rec.Code)
+	}
+}
+
+func TestIdempotencyExpires(t *testing.T) {
+	next := &counter{status: http.StatusCreated}
+	h := NewIdempotency(-time.Second).Middleware(next)
+
+	send(h, "/todos", "{}", "key")
+	send(h, "/todos", "{}", "key")
+	if next.calls.Load() != 2 {
+		t.Errorf("handler called %v times", next.calls.Load())
+	}
+}
+
+func TestIdempotencyServerErrorsNotStored(t *testing.T) {
+	next := &counter{status: http.StatusServiceUnavailable}
+	h := NewIdempotency(time.Minute).Middleware(next)
+
+	send(h, "/todos", "{}", "key")
+	next.status = http.StatusCreated
+	if rec := send(h, "/todos", "{}", "key"); rec.Code != http.StatusCreated || next.calls.Load() != 2 {
+		t.Errorf("retry after a 503: status %v, %v calls", rec.Code, next.calls.Load())
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := NewIdempotency(time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan int)
+	go func() { done <- send(h, "/todos", "{}", "key").Code }()
+	<-started
+
+	if rec := send(h, "/todos", "{}", "key"); rec.Code != http.StatusConflict {
+		t.Errorf("concurrent retry: status %v", rec.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("first request: status %v", code)
+	}
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	panics := true
+	h := NewIdempotency(time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("handler bug")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() {
+			// the panic reaches the server, which logs it
+			if recover() == nil {
+				t.Error("the panic was swallowed")
+			}
+		}()
+		send(h, "/todos", "{}", "key")
+	}()
+
+	// the key is not stuck in flight
+	panics = false
+	if rec := send(h, "/todos", "{}", "key"); rec.Code != http.StatusCreated {
+		t.Errorf("retry after a panic: status %v", rec.Code)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestIdempotencyBody(t *testing.T) {
+	next := &counter{status: http.StatusCreated}
+	h := NewIdempotency(time.Minute).Middleware(next)
+
+	if rec := send(h, "/todos", strings.Repeat("a", maxIdempotentBody+1), "key"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: status %v", rec.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/todos", failingReader{})
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("failed read: status %v", rec.Code)
+	}
+
+	if next.calls.Load() != 0 {
+		t.Errorf("handler called %v times", next.calls.Load())
+	}
+}
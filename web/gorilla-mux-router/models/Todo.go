@@ -1,8 +1,31 @@
 package models
 
+import "time"
+
 type Todo struct {
 	Id        int    `json:"id"`
 	Title     string `json:"title"`
 	Body      string `json:"body"`
 	Completed bool   `json:"completed"`
+	// Due is optional, recurring todos need one
+	Due *time.Time `json:"due,omitempty"`
+	// Priority follows iCalendar: 1 is the highest, 9 the lowest and 0 means none
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	// Recurrence is an RRULE like FREQ=WEEKLY;BYDAY=MO, completing the todo
+	// creates its next occurrence
+	Recurrence string `json:"recurrence,omitempty"`
+	Version    int    `json:"version"`
+}
+
+// Clone returns a copy that doesn't share the due date or the tags
+func (t Todo) Clone() Todo {
+	if t.Due != nil {
+		due := *t.Due
+		t.Due = &due
+	}
+	if t.Tags != nil {
+		t.Tags = append([]string{}, t.Tags...)
+	}
+	return t
 }
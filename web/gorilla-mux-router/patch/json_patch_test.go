@@ -0,0 +1,208 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// sameJSON reports whether two documents hold the same json value
+func sameJSON(t *testing.T, a []byte, b string) bool {
+	t.Helper()
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Fatalf("invalid json %s: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &bv); err != nil {
+		t.Fatalf("invalid json %s: %v", b, err)
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// the examples of RFC 6902, appendix A
+func TestJSONPatchRFCExamples(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name: "A.8 testing a value: success",
+			doc:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[
+				{"op": "test", "path": "/baz", "value": "qux"},
+				{"op": "test", "path": "/foo/1", "value": 2}
+			]`,
+			want: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch([]byte(test.doc), []byte(test.patch))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameJSON(t, got, test.want) {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchAtomic(t *testing.T) {
+	doc := []byte(`{"a": 1}`)
+	_, err := ApplyJSONPatch(doc, []byte(`[{"op": "replace", "path": "/a", "value": 2}, {"op": "remove", "path": "/b"}]`))
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("err = %v", err)
+	}
+	if string(doc) != `{"a": 1}` {
+		t.Errorf("doc changed: %s", doc)
+	}
+}
+
+func TestJSONPatchInvalid(t *testing.T) {
+	for _, patch := range []string{
+		`{"op": "add"}`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "launch", "path": "/a"}]`,
+		`[{"op": "add", "path": "a", "value": 1}]`,
+	} {
+		if _, err := ApplyJSONPatch([]byte(`{}`), []byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%v: err = %v", patch, err)
+		}
+	}
+}
+
+func TestTestNumbers(t *testing.T) {
+	tests := []struct {
+		doc, value string
+		equal      bool
+	}{
+		{"1", "1.0", true},
+		{"100", "1e2", true},
+		{"1.5E+2", "150", true},
+		{"-0", "0", true},
+		{"0.000", "0e10", true},
+		{"0.10", "1e-1", true},
+		{"-12.50", "-125e-1", true},
+		{"1", "-1", false},
+		{"10", "100", false},
+		{"0.1", "0.01", false},
+		// both round to the same float64
+		{"9007199254740993", "9007199254740992", false},
+		{"12345678901234567890123", "12345678901234567890124", false},
+		{"12345678901234567890123", "1.2345678901234567890123e22", true},
+	}
+
+	for _, test := range tests {
+		doc := []byte(`{"n": ` + test.doc + `}`)
+		_, err := ApplyJSONPatch(doc, []byte(`[{"op": "test", "path": "/n", "value": `+test.value+`}]`))
+		if (err == nil) != test.equal {
+			t.Errorf("%v == %v: err = %v", test.doc, test.value, err)
+		}
+	}
+}
+
+func TestNumbersRoundTrip(t *testing.T) {
+	got, err := ApplyJSONPatch([]byte(`{"id": 9007199254740993}`), []byte(`[{"op": "copy", "from": "/id", "path": "/copy"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"copy":9007199254740993,"id":9007199254740993}` {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+	"gorilla-mux-router/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type kind int
+
+const (
+	kindInt kind = iota
+	// kindPriority is an int sorting 0 (none) after the lowest priority
+	kindPriority
+	kindString
+	kindBool
+	kindTime // sortable only, todos without a value sort last
+	kindTags // filterable only, tag=work matches todos having the tag
+)
+
+// field describes a todo field that can be filtered and sorted on
+type field struct {
+	kind  kind
+	value func(models.Todo) interface{}
+}
+
+var fields = map[string]field{
+	"id":        {kindInt, func(t models.Todo) interface{} { return t.Id }},
+	"title":     {kindString, func(t models.Todo) interface{} { return t.Title }},
+	"body":      {kindString, func(t models.Todo) interface{} { return t.Body }},
+	"completed": {kindBool, func(t models.Todo) interface{} { return t.Completed }},
+	"priority":  {kindPriority, func(t models.Todo) interface{} { return t.Priority }},
+	"due":       {kindTime, func(t models.Todo) interface{} { return t.Due }},
+	"tag":       {kindTags, func(t models.Todo) interface{} { return t.Tags }},
+}
+
+// parse converts a raw query value to the type of the field
+func (f field) parse(raw string) (interface{}, error) {
+	switch f.kind {
+	case kindInt, kindPriority:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer")
+		}
+		return n, nil
+	case kindBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return b, nil
+	}
+	return raw, nil
+}
+
+// matches reports whether value equals the filter value, or contains it for tags
+func (f field) matches(value interface{}, filter interface{}) bool {
+	if f.kind == kindTags {
+		for _, tag := range value.([]string) {
+			if strings.EqualFold(tag, filter.(string)) {
+				return true
+			}
+		}
+		return false
+	}
+	return value == filter
+}
+
+// compare returns -1, 0 or 1. Strings compare case-insensitively.
+func (f field) compare(a interface{}, b interface{}) int {
+	switch f.kind {
+	case kindTime:
+		return compareTimes(a.(*time.Time), b.(*time.Time))
+	case kindInt:
+		return compareInts(a.(int), b.(int))
+	case kindPriority:
+		return compareInts(priorityRank(a.(int)), priorityRank(b.(int)))
+	case kindBool:
+		// false sorts before true
+		return compareInts(boolInt(a.(bool)), boolInt(b.(bool)))
+	}
+	return strings.Compare(strings.ToLower(a.(string)), strings.ToLower(b.(string)))
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a *time.Time, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// priorityRank orders priorities from 1 (highest) to 9, then 0 (none)
+func priorityRank(priority int) int {
+	if priority == 0 {
+		return 10
+	}
+	return priority
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
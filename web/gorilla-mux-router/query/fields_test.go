@@ -0,0 +1,71 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFieldParse(t *testing.T) {
+	tests := []struct {
+		field string
+		raw   string
+		want  interface{}
+		err   bool
+	}{
+		{"id", "7", 7, false},
+		{"id", "seven", nil, true},
+		{"completed", "true", true, false},
+		{"completed", "yes", nil, true},
+		{"title", "42", "42", false},
+		{"tag", "work", "work", false},
+	}
+
+	for _, test := range tests {
+		got, err := fields[test.field].parse(test.raw)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("%v=%v: %v, %v", test.field, test.raw, got, err)
+		}
+	}
+}
+
+func TestFieldMatches(t *testing.T) {
+	tags := fields["tag"]
+	if !tags.matches([]string{"home", "Work"}, "work") {
+		t.Error("tags match case-insensitively")
+	}
+	if tags.matches([]string{}, "work") {
+		t.Error("no tags matched")
+	}
+	if !fields["priority"].matches(2, 2) || fields["priority"].matches(2, 3) {
+		t.Error("ints match on equality")
+	}
+}
+
+func TestFieldCompare(t *testing.T) {
+	early := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	tests := []struct {
+		field string
+		a, b  interface{}
+		want  int
+	}{
+		{"id", 1, 2, -1},
+		{"id", 2, 2, 0},
+		{"title", "apple", "Banana", -1},
+		{"title", "ABC", "abc", 0},
+		{"completed", false, true, -1},
+		{"completed", true, false, 1},
+		{"due", &early, &late, -1},
+		// todos without a due date sort last
+		{"due", (*time.Time)(nil), &early, 1},
+		{"due", &late, (*time.Time)(nil), -1},
+		{"due", (*time.Time)(nil), (*time.Time)(nil), 0},
+	}
+
+	for _, test := range tests {
+		if got := fields[test.field].compare(test.a, test.b); got != test.want {
+			t.Errorf("%v: compare(%v, %v) = %v, want %v", test.field, test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// LinkHeader builds the Link header (RFC 8288) of a page, keeping the filters
+// and sort of the request url. It is empty when the query has no limit.
+func (q *Query) LinkHeader(u *url.URL, page Page) string {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		values := u.Query()
+		for key, value := range set {
+			if value == "" {
+				values.Del(key)
+			} else {
+				values.Set(key, value)
+			}
+		}
+		next := *u
+		next.RawQuery = values.Encode()
+		links = append(links, fmt.Sprintf(`<%v>; rel="%v"`, next.String(), rel))
+	}
+
+	if q.Limit == 0 {
+		return ""
+	}
+	limit := strconv.Itoa(q.Limit)
+
+	if !u.Query().Has(paramOffset) {
+		// without an offset pages are linked with cursors, which stay stable
+		// when todos are added or removed
+		link("first", map[string]string{paramCursor: "", paramBefore: "", paramLimit: limit})
+		if page.PrevCursor != "" {
+			link("prev", map[string]string{paramCursor: "", paramBefore: page.PrevCursor, paramLimit: limit})
+		}
+		if page.NextCursor != "" {
+			link("next", map[string]string{paramCursor: page.NextCursor, paramBefore: "", paramLimit: limit})
+		}
+		return strings.Join(links, ", ")
+	}
+
+	link("first", map[string]string{paramOffset: "0", paramLimit: limit})
+	if q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link("prev", map[string]string{paramOffset: strconv.Itoa(prev), paramLimit: limit})
+	}
+	if q.Offset+q.Limit < page.Total {
+		link("next", map[string]string{paramOffset: strconv.Itoa(q.Offset + q.Limit), paramLimit: limit})
+	}
+	if page.Total > 0 {
+		last := (page.Total - 1) / q.Limit * q.Limit
+		link("last", map[string]string{paramOffset: strconv.Itoa(last), paramLimit: limit})
+	}
+	return strings.Join(links, ", ")
+}
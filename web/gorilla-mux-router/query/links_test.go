@@ -0,0 +1,81 @@
+package query
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// links parses a Link header into its urls by rel
+func links(t *testing.T, header string) map[string]url.Values {
+	t.Helper()
+	rels := map[string]url.Values{}
+	if header == "" {
+		return rels
+	}
+	for _, link := range strings.Split(header, ", ") {
+		target, params, _ := strings.Cut(link, "; ")
+		u, err := url.Parse(strings.Trim(target, "<>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(params, `rel="`), `"`)
+		rels[rel] = u.Query()
+	}
+	return rels
+}
+
+func linkHeader(t *testing.T, raw string) map[string]url.Values {
+	t.Helper()
+	u, _ := url.Parse("/todos?" + raw)
+	q := parse(t, u.RawQuery)
+	return links(t, q.LinkHeader(u, q.Apply(todos)))
+}
+
+func TestOffsetLinks(t *testing.T) {
+	rels := linkHeader(t, "tag=home&limit=1&offset=1")
+	want := map[string]string{"first": "0", "prev": "0", "last": "1"}
+	if len(rels) != len(want) {
+		t.Errorf("rels = %v", rels)
+	}
+	for rel, offset := range want {
+		if got := rels[rel].Get("offset"); got != offset {
+			t.Errorf("%v: offset = %q, want %v", rel, got, offset)
+		}
+		// the filters are kept
+		if rels[rel].Get("tag") != "home" || rels[rel].Get("limit") != "1" {
+			t.Errorf("%v: query = %v", rel, rels[rel])
+		}
+	}
+
+	rels = linkHeader(t, "limit=2&offset=0")
+	if rels["next"].Get("offset") != "2" || rels["last"].Get("offset") != "4" || rels["prev"] != nil {
+		t.Errorf("rels = %v", rels)
+	}
+}
+
+func TestCursorLinks(t *testing.T) {
+	rels := linkHeader(t, "sort=title&limit=2")
+	if rels["prev"] != nil || rels["next"].Get("cursor") == "" {
+		t.Fatalf("first page rels = %v", rels)
+	}
+
+	rels = linkHeader(t, rels["next"].Encode())
+	if rels["prev"].Get("before") == "" || rels["prev"].Has("cursor") || rels["next"].Get("cursor") == "" {
+		t.Errorf("middle page rels = %v", rels)
+	}
+	if rels["first"].Has("cursor") || rels["first"].Has("before") || rels["first"].Get("sort") != "title" {
+		t.Errorf("first = %v", rels["first"])
+	}
+
+	prev := linkHeader(t, rels["prev"].Encode())
+	if prev["prev"] != nil || prev["next"].Has("before") {
+		t.Errorf("rels of the page before = %v", prev)
+	}
+}
+
+func TestNoLinksWithoutLimit(t *testing.T) {
+	if rels := linkHeader(t, "sort=title"); len(rels) != 0 {
+		t.Errorf("rels = %v", rels)
+	}
+}
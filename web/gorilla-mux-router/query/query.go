@@ -0,0 +1,337 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gorilla-mux-router/models"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MaxLimit = 1000
+
+// reserved query parameters, every other parameter is a filter
+const (
+	paramSort   = "sort"
+	paramLimit  = "limit"
+	paramOffset = "offset"
+	paramCursor = "cursor"
+	paramBefore = "before"
+)
+
+type Filter struct {
+	Field    string
+	Contains bool // title~=foo matches titles containing foo, case-insensitively
+	Value    interface{}
+}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Query filters, sorts and paginates a list of todos, eg:
+// ?title~=pray&completed=false&tag=work&sort=due,-priority&limit=10&offset=20
+// Instead of an offset, cursor (or before, to go backwards) continues from
+// the cursor of a previous page.
+type Query struct {
+	Filters []Filter
+	Sort    []SortKey
+	Limit   int // 0 means no limit
+	Offset  int
+	// Cursor holds the sort values of the last todo of the previous page
+	Cursor map[string]interface{}
+	// Before holds the sort values of the first todo of the next page, when
+	// going backwards
+	Before map[string]interface{}
+}
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error lists every invalid query parameter
+type Error struct {
+	Params []InvalidParam `json:"invalidParams"`
+}
+
+func (e *Error) Error() string {
+	names := []string{}
+	for _, p := range e.Params {
+		names = append(names, p.Name)
+	}
+	return "invalid query parameters: " + strings.Join(names, ", ")
+}
+
+// Parse reads a Query from the url query, returning an *Error if any parameter is invalid
+func Parse(values url.Values) (*Query, error) {
+	q := &Query{}
+	invalid := &Error{}
+	addInvalid := func(name string, reason string) {
+		invalid.Params = append(invalid.Params, InvalidParam{Name: name, Reason: reason})
+	}
+
+	// sort the keys so errors are reported in a stable order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		raw := values.Get(key)
+
+		switch key {
+		case paramSort:
+			for _, name := range strings.Split(raw, ",") {
+				desc := strings.HasPrefix(name, "-")
+				name = strings.TrimPrefix(name, "-")
+				f, ok := fields[name]
+				if !ok {
+					addInvalid(key, fmt.Sprintf("unknown field %q", name))
+					continue
+				}
+				if f.kind == kindTags {
+					addInvalid(key, fmt.Sprintf("can't sort on %q", name))
+					continue
+				}
+				q.Sort = append(q.Sort, SortKey{Field: name, Desc: desc})
+			}
+		case paramLimit:
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > MaxLimit {
+				addInvalid(key, fmt.Sprintf("expected an integer between 1 and %v", MaxLimit))
+				continue
+			}
+			q.Limit = n
+		case paramOffset:
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				addInvalid(key, "expected a positive integer")
+				continue
+			}
+			q.Offset = n
+		case paramCursor, paramBefore:
+			// decoded once the sort is known
+		default:
+			name, contains := strings.CutSuffix(key, "~")
+			f, ok := fields[name]
+			if !ok {
+				addInvalid(key, "unknown field")
+				continue
+			}
+			if f.kind == kindTime {
+				addInvalid(key, "filtering on dates is not supported")
+				continue
+			}
+			if contains && f.kind != kindString {
+				addInvalid(key, "~= is only supported on text fields")
+				continue
+			}
+			value, err := f.parse(raw)
+			if err != nil {
+				addInvalid(key, err.Error())
+				continue
+			}
+			q.Filters = append(q.Filters, Filter{Field: name, Contains: contains, Value: value})
+		}
+	}
+
+	for _, param := range []string{paramCursor, paramBefore} {
+		raw := values.Get(param)
+		if raw == "" {
+			continue
+		}
+		if values.Has(paramOffset) {
+			addInvalid(param, "cannot be combined with offset")
+		} else if param == paramBefore && values.Has(paramCursor) {
+			addInvalid(param, "cannot be combined with cursor")
+		} else if cursor, err := q.decodeCursor(raw); err != nil {
+			addInvalid(param, err.Error())
+		} else if param == paramCursor {
+			q.Cursor = cursor
+		} else {
+			q.Before = cursor
+		}
+	}
+
+	if len(invalid.Params) > 0 {
+		return nil, invalid
+	}
+	return q, nil
+}
+
+// Page is the result of applying a query
+type Page struct {
+	Todos []models.Todo
+	// Total is the number of todos matching the filters, before pagination
+	Total int
+	// NextCursor is set when there are more todos after the page
+	NextCursor string
+	// PrevCursor is set when there are todos before the page, for cursor
+	// pagination
+	PrevCursor string
+}
+
+func (q *Query) Apply(todos []models.Todo) Page {
+	matching := []models.Todo{}
+	for _, todo := range todos {
+		if q.matches(todo) {
+			matching = append(matching, todo)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return q.compare(matching[i], fieldValues(matching[j], q.sortFields())) < 0
+	})
+
+	page := Page{Total: len(matching)}
+
+	start, end := q.bounds(matching)
+	page.Todos = matching[start:end]
+	if len(page.Todos) == 0 {
+		return page
+	}
+	if end < len(matching) {
+		page.NextCursor = q.encodeCursor(page.Todos[len(page.Todos)-1])
+	}
+	if start > 0 && q.Offset == 0 {
+		page.PrevCursor = q.encodeCursor(page.Todos[0])
+	}
+	return page
+}
+
+// bounds returns the range of the sorted todos in the page
+func (q *Query) bounds(matching []models.Todo) (int, int) {
+	if q.Before != nil {
+		// the limit todos right before the cursor
+		end := sort.Search(len(matching), func(i int) bool {
+			return q.compare(matching[i], q.Before) >= 0
+		})
+		start := 0
+		if q.Limit > 0 && end-q.Limit > 0 {
+			start = end - q.Limit
+		}
+		return start, end
+	}
+
+	start := q.Offset
+	if q.Cursor != nil {
+		start = sort.Search(len(matching), func(i int) bool {
+			return q.compare(matching[i], q.Cursor) > 0
+		})
+	}
+	if start > len(matching) {
+		start = len(matching)
+	}
+
+	end := len(matching)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+	return start, end
+}
+
+func (q *Query) matches(todo models.Todo) bool {
+	for _, filter := range q.Filters {
+		value := fields[filter.Field].value(todo)
+		if filter.Contains {
+			if !strings.Contains(strings.ToLower(value.(string)), strings.ToLower(filter.Value.(string))) {
+				return false
+			}
+		} else if !fields[filter.Field].matches(value, filter.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortFields returns the sort keys with id appended as a tie breaker, so the
+// order (and therefore the cursor) is total
+func (q *Query) sortFields() []SortKey {
+	for _, key := range q.Sort {
+		if key.Field == "id" {
+			return q.Sort
+		}
+	}
+	return append(append([]SortKey{}, q.Sort...), SortKey{Field: "id"})
+}
+
+// compare compares a todo with the sort values of another todo (or a cursor)
+func (q *Query) compare(todo models.Todo, values map[string]interface{}) int {
+	for _, key := range q.sortFields() {
+		f := fields[key.Field]
+		c := f.compare(f.value(todo), values[key.Field])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func fieldValues(todo models.Todo, keys []SortKey) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		values[key.Field] = fields[key.Field].value(todo)
+	}
+	return values
+}
+
+// the cursor is the base64 encoded json of the sort values of the last todo
+func (q *Query) encodeCursor(todo models.Todo) string {
+	content, _ := json.Marshal(fieldValues(todo, q.sortFields()))
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func (q *Query) decodeCursor(raw string) (map[string]interface{}, error) {
+	content, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(content, &encoded); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	values := map[string]interface{}{}
+	for _, key := range q.sortFields() {
+		raw, ok := encoded[key.Field]
+		if !ok {
+			return nil, fmt.Errorf("cursor does not match the sort order")
+		}
+
+		var value interface{}
+		switch fields[key.Field].kind {
+		case kindInt, kindPriority:
+			var n int
+			err = json.Unmarshal(raw, &n)
+			value = n
+		case kindBool:
+			var b bool
+			err = json.Unmarshal(raw, &b)
+			value = b
+		case kindTime:
+			var t *time.Time
+			err = json.Unmarshal(raw, &t)
+			value = t
+		default:
+			var s string
+			err = json.Unmarshal(raw, &s)
+			value = s
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+		values[key.Field] = value
+	}
+	return values, nil
+}
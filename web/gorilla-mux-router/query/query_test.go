@@ -0,0 +1,170 @@
+package query
+
+import (
+	"errors"
+	"gorilla-mux-router/models"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(day int) *time.Time {
+	t := time.Date(2026, time.March, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+var todos = []models.Todo{
+	{Id: 1, Title: "Buy milk", Priority: 2, Tags: []string{"home"}, Due: date(3)},
+	{Id: 2, Title: "write report", Priority: 1, Tags: []string{"Work"}, Completed: true},
+	{Id: 3, Title: "Call mum", Priority: 2, Due: date(1)},
+	{Id: 4, Title: "buy stamps", Priority: 5, Tags: []string{"home", "work"}, Due: date(2)},
+	{Id: 5, Title: "Plan trip", Priority: 0},
+}
+
+func parse(t *testing.T, raw string) *Query {
+	t.Helper()
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := Parse(values)
+	if err != nil {
+		t.Fatalf("%v: %v", raw, err)
+	}
+	return q
+}
+
+func ids(todos []models.Todo) []int {
+	ids := []int{}
+	for _, todo := range todos {
+		ids = append(ids, todo.Id)
+	}
+	return ids
+}
+
+func TestApply(t *testing.T) {
+	tests := map[string][]int{
+		"":                            {1, 2, 3, 4, 5},
+		"title~=BUY":                  {1, 4},
+		"completed=true":              {2},
+		"tag=work":                    {2, 4},
+		"tag=home&priority=2":         {1},
+		"sort=priority":               {2, 1, 3, 4, 5},
+		"sort=-priority":              {5, 4, 1, 3, 2},
+		"sort=title":                  {1, 4, 3, 5, 2},
+		"sort=due":                    {3, 4, 1, 2, 5},
+		"sort=-due":                   {2, 5, 1, 4, 3},
+		"sort=completed,-id":          {5, 4, 3, 1, 2},
+		"sort=priority&limit=2":       {2, 1},
+		"sort=priority&offset=3":      {4, 5},
+		"priority=0":                  {5},
+		"limit=2&offset=4":            {5},
+		"offset=10":                   {},
+		"title~=buy&sort=-id&limit=1": {4},
+	}
+
+	for raw, want := range tests {
+		page := parse(t, raw).Apply(todos)
+		if got := ids(page.Todos); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: ids = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestApplyTotal(t *testing.T) {
+	page := parse(t, "tag=home&limit=1").Apply(todos)
+	if page.Total != 2 || len(page.Todos) != 1 {
+		t.Errorf("total = %v, todos = %v", page.Total, ids(page.Todos))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	values, _ := url.ParseQuery("sort=tag,nope&limit=0&offset=-1&color=red&due=2026-01-01&priority~=1&completed=maybe")
+	_, err := Parse(values)
+
+	var queryErr *Error
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("err = %v", err)
+	}
+	var names []string
+	for _, p := range queryErr.Params {
+		names = append(names, p.Name)
+	}
+	want := []string{"color", "completed", "due", "limit", "offset", "priority~", "sort", "sort"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("invalid params = %v, want %v", names, want)
+	}
+}
+
+func TestCursorErrors(t *testing.T) {
+	cursor := parse(t, "limit=1").Apply(todos).NextCursor
+
+	for _, raw := range []string{
+		"cursor=" + cursor + "&offset=1",
+		"before=" + cursor + "&cursor=" + cursor,
+		"cursor=not-base64!",
+		// the cursor has no title, it was made for another sort
+		"sort=title&cursor=" + cursor,
+		"sort=title&before=" + cursor,
+	} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := Parse(values); err == nil {
+			t.Errorf("%q: expected an error", raw)
+		}
+	}
+}
+
+// TestCursorPagination follows the cursors from the first page, forwards
+// then backwards
+func TestCursorPagination(t *testing.T) {
+	for _, sort := range []string{"", "sort=-priority", "sort=due", "sort=title"} {
+		all := ids(parse(t, sort).Apply(todos).Todos)
+
+		var pages [][]int
+		var cursors []Page
+		raw := sort + "&limit=2"
+		for {
+			page := parse(t, raw).Apply(todos)
+			pages = append(pages, ids(page.Todos))
+			cursors = append(cursors, page)
+			if page.NextCursor == "" {
+				break
+			}
+			raw = sort + "&limit=2&cursor=" + page.NextCursor
+		}
+
+		var seen []int
+		for _, page := range pages {
+			seen = append(seen, page...)
+		}
+		if !reflect.DeepEqual(seen, all) || len(pages) != 3 {
+			t.Errorf("%q: pages = %v, want %v", sort, pages, all)
+		}
+		if cursors[0].PrevCursor != "" {
+			t.Errorf("%q: the first page has a prev cursor", sort)
+		}
+
+		// going back from the last page gives the same pages
+		for i := len(pages) - 1; i > 0; i-- {
+			prev := parse(t, sort+"&limit=2&before="+cursors[i].PrevCursor).Apply(todos)
+			if got := ids(prev.Todos); !reflect.DeepEqual(got, pages[i-1]) {
+				t.Errorf("%q: page before %v = %v, want %v", sort, pages[i], got, pages[i-1])
+			}
+			if prev.NextCursor == "" || (i > 1) != (prev.PrevCursor != "") {
+				t.Errorf("%q: cursors of page %v = %q, %q", sort, ids(prev.Todos), prev.PrevCursor, prev.NextCursor)
+			}
+		}
+	}
+}
+
+func TestCursorStableOnInsert(t *testing.T) {
+	page := parse(t, "limit=2").Apply(todos)
+
+	// a todo added before the cursor does not shift the next page
+	more := append([]models.Todo{{Id: 0, Title: "new"}}, todos...)
+	next := parse(t, "limit=2&cursor="+page.NextCursor).Apply(more)
+	if got := ids(next.Todos); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("next page = %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is the subset of an RFC 5545 RRULE supported for todos:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY (weekly rules),
+// BYMONTHDAY (monthly rules), COUNT and UNTIL, eg: FREQ=WEEKLY;BYDAY=MO,WE
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	// Count is the number of occurrences left including the current one, 0 if unbounded
+	Count int
+	Until *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+const untilLayout = "20060102T150405Z"
+
+func Parse(rule string) (*Rule, error) {
+	r := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		if part == "" {
+			// eg: a trailing ;
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			switch r.Freq {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY %q, expected MO, TU, WE, TH, FR, SA or SU", day)
+				}
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY must be between 1 and 31 or -31 and -1")
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL can't be combined")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		// a date includes the whole day
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a date (20060102) or a UTC time (20060102T150405Z)")
+}
+
+// String formats the rule back to RRULE syntax
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, weekday := range r.ByDay {
+			for name, d := range weekdays {
+				if d == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence following current, which must itself be an
+// occurrence of the rule, and the rule of the remaining series (with COUNT
+// decremented). ok is false when the series is over.
+func (r *Rule) Next(current time.Time) (next time.Time, rest *Rule, ok bool) {
+	if r.Count == 1 {
+		return time.Time{}, nil, false
+	}
+
+	switch r.Freq {
+	case "DAILY":
+		next = current.AddDate(0, 0, r.Interval)
+	case "WEEKLY":
+		next = r.nextWeekly(current)
+	case "MONTHLY":
+		next = r.nextMonthly(current)
+	case "YEARLY":
+		next = r.nextYearly(current)
+	}
+
+	if next.IsZero() || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, nil, false
+	}
+
+	rest = r.copy()
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, rest, true
+}
+
+func (r *Rule) copy() *Rule {
+	c := *r
+	c.ByDay = append([]time.Weekday{}, r.ByDay...)
+	c.ByMonthDay = append([]int{}, r.ByMonthDay...)
+	return &c
+}
+
+func (r *Rule) nextWeekly(current time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return current.AddDate(0, 0, 7*r.Interval)
+	}
+
+	// weeks start on monday (the RFC 5545 default WKST)
+	week := startOfWeek(current)
+	for offset := 1; offset <= 7*r.Interval+7; offset++ {
+		candidate := current.AddDate(0, 0, offset)
+		weeks := int(startOfWeek(candidate).Sub(week).Hours()/24+0.5) / 7
+		if weeks%r.Interval != 0 {
+			continue
+		}
+		for _, weekday := range r.ByDay {
+			if candidate.Weekday() == weekday {
+				return candidate
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (r *Rule) nextMonthly(current time.Time) time.Time {
+	days := r.ByMonthDay
+	if len(days) == 0 {
+		days = []int{current.Day()}
+	}
+
+	// months without a matching day (eg: the 31st in april) are skipped, as in RFC 5545
+	for months := 0; months <= 12*r.Interval*4; months += r.Interval {
+		first := time.Date(current.Year(), current.Month()+time.Month(months), 1, current.Hour(), current.Minute(), current.Second(), 0, current.Location())
+		length := first.AddDate(0, 1, -1).Day()
+
+		var candidates []int
+		for _, day := range days {
+			if day < 0 {
+				day = length + day + 1
+			}
+			if day >= 1 && day <= length {
+				candidates = append(candidates, day)
+			}
+		}
+		sort.Ints(candidates)
+
+		for _, day := range candidates {
+			candidate := first.AddDate(0, 0, day-1)
+			if candidate.After(current) {
+				return candidate
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (r *Rule) nextYearly(current time.Time) time.Time {
+	// february 29th only happens on leap years
+	for years := r.Interval; years <= r.Interval*8; years += r.Interval {
+		candidate := time.Date(current.Year()+years, current.Month(), current.Day(), current.Hour(), current.Minute(), current.Second(), 0, current.Location())
+		if candidate.Day() == current.Day() {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
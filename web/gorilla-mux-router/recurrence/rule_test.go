@@ -0,0 +1,140 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+// occurrences follows the rule from start, returning at most n dates
+func occurrences(t *testing.T, rule string, start time.Time, n int) []string {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("%v: %v", rule, err)
+	}
+
+	dates := []string{start.Format("Mon 2006-01-02")}
+	current := start
+	for len(dates) < n {
+		next, rest, ok := r.Next(current)
+		if !ok {
+			break
+		}
+		dates = append(dates, next.Format("Mon 2006-01-02"))
+		current, r = next, rest
+	}
+	return dates
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start time.Time
+		want  []string
+		// ends is set when the series is over after want
+		ends bool
+	}{
+		{"FREQ=DAILY;INTERVAL=3", date(2026, time.February, 26), []string{"Thu 2026-02-26", "Sun 2026-03-01", "Wed 2026-03-04"}, false},
+		{"FREQ=WEEKLY", date(2026, time.March, 2), []string{"Mon 2026-03-02", "Mon 2026-03-09", "Mon 2026-03-16"}, false},
+		// the days of the week in any order, from the current one on
+		{"FREQ=WEEKLY;BYDAY=FR,MO,WE", date(2026, time.March, 4), []string{"Wed 2026-03-04", "Fri 2026-03-06", "Mon 2026-03-09", "Wed 2026-03-11"}, false},
+		// every other week, counted from the week of the start
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU", date(2026, time.March, 3), []string{"Tue 2026-03-03", "Sun 2026-03-08", "Tue 2026-03-17", "Sun 2026-03-22", "Tue 2026-03-31"}, false},
+		// months without the day are skipped, as in RFC 5545
+		{"FREQ=MONTHLY", date(2026, time.January, 31), []string{"Sat 2026-01-31", "Tue 2026-03-31", "Sun 2026-05-31", "Fri 2026-07-31"}, false},
+		{"FREQ=MONTHLY;BYMONTHDAY=30", date(2026, time.January, 30), []string{"Fri 2026-01-30", "Mon 2026-03-30", "Thu 2026-04-30"}, false},
+		// -1 clamps to the last day of every month
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", date(2028, time.January, 31), []string{"Mon 2028-01-31", "Tue 2028-02-29", "Fri 2028-03-31", "Sun 2028-04-30"}, false},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,1", date(2026, time.March, 1), []string{"Sun 2026-03-01", "Sun 2026-03-15", "Wed 2026-04-01"}, false},
+		{"FREQ=MONTHLY;INTERVAL=5", date(2026, time.October, 18), []string{"Sun 2026-10-18", "Thu 2027-03-18", "Wed 2027-08-18"}, false},
+		{"FREQ=YEARLY", date(2028, time.February, 29), []string{"Tue 2028-02-29", "Sun 2032-02-29"}, false},
+		// the series ends with the last counted occurrence
+		{"FREQ=DAILY;COUNT=3", date(2026, time.March, 1), []string{"Sun 2026-03-01", "Mon 2026-03-02", "Tue 2026-03-03"}, true},
+		{"FREQ=WEEKLY;BYDAY=MO,TH;COUNT=1", date(2026, time.March, 2), []string{"Mon 2026-03-02"}, true},
+		// UNTIL is inclusive, a date includes the whole day
+		{"FREQ=DAILY;UNTIL=20260303", date(2026, time.March, 1), []string{"Sun 2026-03-01", "Mon 2026-03-02", "Tue 2026-03-03"}, true},
+		{"FREQ=DAILY;UNTIL=20260303T093000Z", date(2026, time.March, 1), []string{"Sun 2026-03-01", "Mon 2026-03-02", "Tue 2026-03-03"}, true},
+		{"FREQ=DAILY;UNTIL=20260303T092959Z", date(2026, time.March, 1), []string{"Sun 2026-03-01", "Mon 2026-03-02"}, true},
+		{"FREQ=MONTHLY;UNTIL=20260415", date(2026, time.January, 31), []string{"Sat 2026-01-31", "Tue 2026-03-31"}, true},
+	}
+
+	for _, test := range tests {
+		n := len(test.want)
+		if test.ends {
+			n++
+		}
+		got := occurrences(t, test.rule, test.start, n)
+		if len(got) != len(test.want) {
+			t.Errorf("%v: %q, want %q", test.rule, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: %q, want %q", test.rule, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNextCount(t *testing.T) {
+	r, _ := Parse("FREQ=DAILY;COUNT=3")
+	_, rest, _ := r.Next(date(2026, time.March, 1))
+	if rest.Count != 2 || r.Count != 3 {
+		t.Errorf("count = %v, rule left with %v", rest.Count, r.Count)
+	}
+	if rest.String() != "FREQ=DAILY;COUNT=2" {
+		t.Errorf("rest = %v", rest)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"FREQ=DAILY":                                "FREQ=DAILY",
+		"RRULE:freq=weekly;byday=mo,fr":             "FREQ=WEEKLY;BYDAY=MO,FR",
+		"FREQ=WEEKLY;BYDAY=MO;":                     "FREQ=WEEKLY;BYDAY=MO",
+		"FREQ=MONTHLY;;INTERVAL=1;BYMONTHDAY=-1,15": "FREQ=MONTHLY;BYMONTHDAY=-1,15",
+		"FREQ=YEARLY;INTERVAL=2;COUNT=4":            "FREQ=YEARLY;INTERVAL=2;COUNT=4",
+		"FREQ=DAILY;UNTIL=20260301T120000Z":         "FREQ=DAILY;UNTIL=20260301T120000Z",
+		"FREQ=DAILY;UNTIL=20260301":                 "FREQ=DAILY;UNTIL=20260301T235959Z",
+	}
+	for rule, want := range tests {
+		r, err := Parse(rule)
+		if err != nil {
+			t.Errorf("%v: %v", rule, err)
+			continue
+		}
+		if r.String() != want {
+			t.Errorf("%v: String() = %v, want %v", rule, r.String(), want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		";",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260301",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;WKST=MO",
+		"FREQ=DAILY;COUNT",
+		"FREQ=DAILY;COUNT=",
+	} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("%q: expected an error", rule)
+		}
+	}
+}
@@ -1,14 +1,48 @@
 package routes
 
 import (
+	"gorilla-mux-router/events"
 	"gorilla-mux-router/handlers"
+	"gorilla-mux-router/middleware"
+	"gorilla-mux-router/search"
+	"gorilla-mux-router/store"
+	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes() *mux.Router {
+type Config struct {
+	Store store.TodoStore
+	// Broker receives every change, it is streamed at /todos/events
+	Broker *events.Broker
+	// Index must be fed the events of Broker, it is queried at /todos/search
+	Index *search.Index
+	// IdempotencyWindow is how long responses are kept for retries with the
+	// same Idempotency-Key
+	IdempotencyWindow time.Duration
+}
+
+func RegisterRoutes(config Config) *mux.Router {
 	router := mux.NewRouter()
+	h := handlers.NewTodoHandler(config.Store, config.Broker, config.Index)
+	idempotency := middleware.NewIdempotency(config.IdempotencyWindow)
+
+	router.HandleFunc("/", h.GetAllTodos).Methods("GET")
+	router.HandleFunc("/todos.ics", h.ExportCalendar).Methods("GET")
+	router.Handle("/todos:batch", idempotency.Middleware(http.HandlerFunc(h.BatchTodos))).Methods("POST")
 
-	router.HandleFunc("/", handlers.GetAllTodos).Methods("GET")
+	todos := router.PathPrefix("/todos").Subrouter()
+	todos.HandleFunc("", h.GetAllTodos).Methods("GET")
+	todos.HandleFunc("/events", h.TodoEvents).Methods("GET")
+	todos.HandleFunc("/search", h.SearchTodos).Methods("GET")
+	todos.HandleFunc("/overdue", h.GetOverdueTodos).Methods("GET")
+	todos.HandleFunc("/due-this-week", h.GetTodosDueThisWeek).Methods("GET")
+	todos.HandleFunc("/tags/{tag}", h.GetTodosByTag).Methods("GET")
+	todos.Handle("", idempotency.Middleware(http.HandlerFunc(h.CreateTodo))).Methods("POST")
+	todos.HandleFunc("/{id:[0-9]+}", h.GetTodo).Methods("GET")
+	todos.HandleFunc("/{id:[0-9]+}", h.UpdateTodo).Methods("PUT")
+	todos.HandleFunc("/{id:[0-9]+}", h.PatchTodo).Methods("PATCH")
+	todos.HandleFunc("/{id:[0-9]+}", h.DeleteTodo).Methods("DELETE")
 	return router
 }
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+)
+
+// word is a word of a text with its position, so it can be highlighted
+type word struct {
+	text  string
+	start int
+	end   int
+}
+
+// words splits text on everything that is not a letter or a digit
+func words(text string) []word {
+	var result []word
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\''
+		if isWordRune && start < 0 {
+			start = i
+		}
+		if !isWordRune && start >= 0 {
+			result = append(result, word{text: text[start:i], start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{text: text[start:], start: start, end: len(text)})
+	}
+	return result
+}
+
+// term normalises a word to the term stored in the index: lower case and
+// stemmed, eg: "Running" and "runs" are both "run". Stop words give "".
+func term(w string) string {
+	w = strings.ToLower(strings.Trim(w, "'"))
+	if w == "" || english.IsStopWord(w) {
+		return ""
+	}
+	return english.Stem(w, false)
+}
+
+// analyze returns the terms of a text, stop words removed
+func analyze(text string) []string {
+	var terms []string
+	for _, w := range words(text) {
+		if t := term(w.text); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
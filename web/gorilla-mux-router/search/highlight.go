@@ -0,0 +1,83 @@
+package search
+
+import (
+	"gorilla-mux-router/models"
+	"html"
+	"strings"
+)
+
+// snippetWords is the number of words kept around the first match of a long body
+const snippetWords = 12
+
+// highlights returns the title and body with matching words wrapped in
+// <mark>. The text is html escaped; a long body is cut around its first match.
+func highlights(todo models.Todo, clauses []clause) map[string]string {
+	result := map[string]string{}
+	if title, ok := highlight(todo.Title, clauses, 0); ok {
+		result["title"] = title
+	}
+	if body, ok := highlight(todo.Body, clauses, snippetWords); ok {
+		result["body"] = body
+	}
+	return result
+}
+
+func highlight(text string, clauses []clause, window int) (string, bool) {
+	ws := words(text)
+
+	first := -1
+	matched := make([]bool, len(ws))
+	for n, w := range ws {
+		if matches(w.text, clauses) {
+			matched[n] = true
+			if first < 0 {
+				first = n
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from, to := 0, len(ws)
+	if window > 0 {
+		from = max(0, first-window/2)
+		to = min(len(ws), from+window)
+	}
+
+	var sb strings.Builder
+	if from > 0 {
+		sb.WriteString("…")
+	}
+	start := ws[from].start
+	if from == 0 {
+		start = 0
+	}
+	for n := from; n < to; n++ {
+		w := ws[n]
+		sb.WriteString(html.EscapeString(text[start:w.start]))
+		if matched[n] {
+			sb.WriteString("<mark>" + html.EscapeString(w.text) + "</mark>")
+		} else {
+			sb.WriteString(html.EscapeString(w.text))
+		}
+		start = w.end
+	}
+	if to < len(ws) {
+		sb.WriteString("…")
+	} else {
+		sb.WriteString(html.EscapeString(text[start:]))
+	}
+	return sb.String(), true
+}
+
+func matches(w string, clauses []clause) bool {
+	t := term(w)
+	lower := strings.ToLower(w)
+	for _, c := range clauses {
+		if (t != "" && t == c.term) || (c.prefix != "" && (strings.HasPrefix(lower, c.prefix) || strings.HasPrefix(t, c.prefix))) {
+			return true
+		}
+	}
+	return false
+}
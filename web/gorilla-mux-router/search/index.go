@@ -0,0 +1,298 @@
+package search
+
+import (
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// BM25 parameters
+	k1 = 1.2
+	b  = 0.75
+
+	// a match in the title counts as much as titleWeight matches in the body
+	titleWeight = 2
+
+	// maxDeleted is the number of deleted todos remembered, late events only
+	// come from the few changes in flight
+	maxDeleted = 1000
+)
+
+type frequencies struct {
+	title int
+	body  int
+}
+
+func (f frequencies) weighted() float64 {
+	return float64(titleWeight*f.title + f.body)
+}
+
+type document struct {
+	todo   models.Todo
+	length float64 // weighted number of terms
+	terms  []string
+}
+
+// Index is an in-memory inverted index over the title and body of todos,
+// ranking results with BM25
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[int]*document
+	postings map[string]map[int]frequencies
+	terms    []string // sorted, for prefix matching
+	totalLen float64
+	// deleted holds the version of the last deleted todos, so an event for
+	// an older version arriving late doesn't bring them back
+	deleted      map[int]int
+	deletedOrder []deletion // oldest first, to forget them
+}
+
+type deletion struct {
+	id      int
+	version int
+}
+
+type Result struct {
+	Todo       models.Todo       `json:"todo"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[int]*document{},
+		postings: map[string]map[int]frequencies{},
+		deleted:  map[int]int{},
+	}
+}
+
+// Apply keeps the index in sync with a change published on the broker.
+// Updates and deletes of a version at or below the indexed one are ignored,
+// so the index can follow the broker before being loaded from the store.
+// Created always indexes the todo: a deleted id can be given again, with
+// its version starting over.
+func (i *Index) Apply(event events.Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	todo := event.Todo
+	if event.Type == events.Created {
+		delete(i.deleted, todo.Id)
+		i.put(todo)
+		return
+	}
+
+	indexed := i.deleted[todo.Id]
+	if doc, ok := i.docs[todo.Id]; ok {
+		indexed = doc.todo.Version
+	}
+
+	if event.Type == events.Deleted {
+		// the todo is gone even if the event missed its last update
+		if todo.Version >= indexed {
+			i.remove(todo.Id)
+			i.recordDeletion(todo.Id, todo.Version)
+		}
+		return
+	}
+	if todo.Version > indexed {
+		i.put(todo)
+	}
+}
+
+// recordDeletion remembers a deleted todo, dropping the oldest records past maxDeleted
+func (i *Index) recordDeletion(id int, version int) {
+	i.deleted[id] = version
+	i.deletedOrder = append(i.deletedOrder, deletion{id, version})
+	for len(i.deletedOrder) > maxDeleted {
+		oldest := i.deletedOrder[0]
+		i.deletedOrder = i.deletedOrder[1:]
+		if i.deleted[oldest.id] == oldest.version {
+			delete(i.deleted, oldest.id)
+		}
+	}
+}
+
+// Put adds or replaces a todo
+func (i *Index) Put(todo models.Todo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.put(todo)
+}
+
+func (i *Index) put(todo models.Todo) {
+	i.remove(todo.Id)
+
+	freqs := map[string]frequencies{}
+	titleTerms := analyze(todo.Title)
+	bodyTerms := analyze(todo.Body)
+	for _, t := range titleTerms {
+		f := freqs[t]
+		f.title++
+		freqs[t] = f
+	}
+	for _, t := range bodyTerms {
+		f := freqs[t]
+		f.body++
+		freqs[t] = f
+	}
+
+	doc := &document{todo: todo, length: float64(titleWeight*len(titleTerms) + len(bodyTerms))}
+	for t, f := range freqs {
+		if i.postings[t] == nil {
+			i.postings[t] = map[int]frequencies{}
+			i.insertTerm(t)
+		}
+		i.postings[t][todo.Id] = f
+		doc.terms = append(doc.terms, t)
+	}
+
+	i.docs[todo.Id] = doc
+	i.totalLen += doc.length
+}
+
+func (i *Index) Remove(id int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id int) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, t := range doc.terms {
+		delete(i.postings[t], id)
+		if len(i.postings[t]) == 0 {
+			delete(i.postings, t)
+			i.deleteTerm(t)
+		}
+	}
+	i.totalLen -= doc.length
+	delete(i.docs, id)
+}
+
+func (i *Index) insertTerm(t string) {
+	at := sort.SearchStrings(i.terms, t)
+	i.terms = append(i.terms, "")
+	copy(i.terms[at+1:], i.terms[at:])
+	i.terms[at] = t
+}
+
+func (i *Index) deleteTerm(t string) {
+	at := sort.SearchStrings(i.terms, t)
+	if at < len(i.terms) && i.terms[at] == t {
+		i.terms = append(i.terms[:at], i.terms[at+1:]...)
+	}
+}
+
+// clause is a word of the query, matching its stemmed term and, for prefix
+// words, every indexed term starting with the prefix
+type clause struct {
+	term   string
+	prefix string
+}
+
+// parseQuery splits a query into clauses. Words ending with * are prefixes,
+// and so is the last word, so results show up while the user is typing.
+func parseQuery(q string) []clause {
+	var clauses []clause
+	fields := strings.Fields(q)
+	for n, field := range fields {
+		explicit := strings.HasSuffix(field, "*")
+		ws := words(strings.TrimSuffix(field, "*"))
+		for j, w := range ws {
+			last := n == len(fields)-1 && j == len(ws)-1
+			c := clause{term: term(w.text)}
+			if explicit || last {
+				c.prefix = strings.ToLower(w.text)
+			}
+			if c.term != "" || (c.prefix != "" && (explicit || len(c.prefix) > 1)) {
+				clauses = append(clauses, c)
+			}
+		}
+	}
+	return clauses
+}
+
+// Search returns the todos matching any word of q, best first
+func (i *Index) Search(q string, limit int) []Result {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	clauses := parseQuery(q)
+	if len(clauses) == 0 || len(i.docs) == 0 {
+		return []Result{}
+	}
+
+	avgLen := i.totalLen / float64(len(i.docs))
+	scores := map[int]float64{}
+
+	for _, c := range clauses {
+		// a document scores the best of the terms the clause matches, so a
+		// word doesn't count twice through its stem and its prefix
+		best := map[int]float64{}
+		for _, t := range i.expand(c) {
+			postings := i.postings[t]
+			idf := math.Log(1 + (float64(len(i.docs))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for id, f := range postings {
+				tf := f.weighted()
+				norm := tf + k1*(1-b+b*i.docs[id].length/avgLen)
+				score := idf * tf * (k1 + 1) / norm
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		todo := i.docs[id].todo
+		results = append(results, Result{
+			Todo:       todo,
+			Score:      math.Round(score*1000) / 1000,
+			Highlights: highlights(todo, clauses),
+		})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Todo.Id < results[b].Todo.Id
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// expand returns the indexed terms a clause matches
+func (i *Index) expand(c clause) []string {
+	var terms []string
+	if _, ok := i.postings[c.term]; ok && c.term != "" {
+		terms = append(terms, c.term)
+	}
+	if c.prefix == "" {
+		return terms
+	}
+
+	for at := sort.SearchStrings(i.terms, c.prefix); at < len(i.terms) && strings.HasPrefix(i.terms[at], c.prefix); at++ {
+		if i.terms[at] != c.term {
+			terms = append(terms, i.terms[at])
+		}
+	}
+	return terms
+}
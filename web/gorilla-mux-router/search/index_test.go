@@ -0,0 +1,216 @@
+package search
+
+import (
+	"gorilla-mux-router/events"
+	"gorilla-mux-router/models"
+	"reflect"
+	"testing"
+)
+
+func newTestIndex(todos ...models.Todo) *Index {
+	index := NewIndex()
+	for n, todo := range todos {
+		todo.Id = n + 1
+		index.Put(todo)
+	}
+	return index
+}
+
+func ids(results []Result) []int {
+	ids := []int{}
+	for _, r := range results {
+		ids = append(ids, r.Todo.Id)
+	}
+	return ids
+}
+
+// sameIds compares ids ignoring their order, for results with equal scores
+func sameIds(a []int, b []int) bool {
+	seen := map[int]bool{}
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestSearchRanking(t *testing.T) {
+	index := newTestIndex(
+		models.Todo{Title: "Groceries", Body: "milk, eggs and bread"},
+		models.Todo{Title: "Milk", Body: "buy milk on the way home"},
+		models.Todo{Title: "Errands", Body: "post office, bank, pharmacy, milk, hardware store, dry cleaning and the garage"},
+		models.Todo{Title: "Read a book"},
+	)
+
+	tests := map[string][]int{
+		// a match in the title counts more, a long body dilutes a match
+		"milk": {2, 1, 3},
+		// the rarer term weighs more than the common one
+		"milk bread": {1, 2, 3},
+		"book":       {4},
+		"the":        {},
+		"unknown":    {},
+	}
+	for q, want := range tests {
+		if got := ids(index.Search(q, 0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: ids = %v, want %v", q, got, want)
+		}
+	}
+
+	if got := index.Search("milk", 2); len(got) != 2 {
+		t.Errorf("limit 2: %v results", len(got))
+	}
+	if got := NewIndex().Search("milk", 0); got == nil || len(got) != 0 {
+		t.Errorf("empty index: %v", got)
+	}
+}
+
+func TestSearchStemming(t *testing.T) {
+	index := newTestIndex(
+		models.Todo{Title: "Running shoes"},
+		models.Todo{Title: "Go for a run"},
+		models.Todo{Title: "Plan the runway show"},
+	)
+
+	// the words are not last, they are not prefixes of runway
+	for _, q := range []string{"run now", "runs now", "RUNNING now"} {
+		if got := ids(index.Search(q, 0)); !sameIds(got, []int{1, 2}) {
+			t.Errorf("%q: ids = %v", q, got)
+		}
+	}
+}
+
+func TestSearchPrefix(t *testing.T) {
+	index := newTestIndex(
+		models.Todo{Title: "Dentist appointment"},
+		models.Todo{Title: "Call the dentist's office"},
+		models.Todo{Title: "Departure at noon"},
+	)
+
+	tests := map[string][]int{
+		// the last word is a prefix while the user is typing
+		"dent":     {1, 2},
+		"de":       {1, 2, 3},
+		"dent noo": {3},
+		// other words only with an explicit *
+		"dep* call": {2, 3},
+		"dep call":  {2},
+		"d*":        {1, 2, 3},
+		"x*":        {},
+	}
+	for q, want := range tests {
+		if got := ids(index.Search(q, 0)); !sameIds(got, want) {
+			t.Errorf("%q: ids = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	body := "one two three four five six seven eight nine ten eleven twelve thirteen <milk> fourteen fifteen sixteen seventeen eighteen nineteen twenty"
+	index := newTestIndex(
+		models.Todo{Title: "Buy milk & Milkshakes", Body: body},
+		models.Todo{Title: "Shopping", Body: "milk"},
+	)
+
+	results := index.Search("milk", 0)
+	want := map[int]map[string]string{
+		1: {
+			"title": "Buy <mark>milk</mark> &amp; <mark>Milkshakes</mark>",
+			"body":  "…eight nine ten eleven twelve thirteen &lt;<mark>milk</mark>&gt; fourteen fifteen sixteen seventeen eighteen…",
+		},
+		2: {"body": "<mark>milk</mark>"},
+	}
+	for _, r := range results {
+		if !reflect.DeepEqual(r.Highlights, want[r.Todo.Id]) {
+			t.Errorf("todo %v: highlights = %q, want %q", r.Todo.Id, r.Highlights, want[r.Todo.Id])
+		}
+	}
+
+	// milk is only a prefix as the last word
+	results = index.Search("milk bread", 0)
+	if len(results) != 2 || results[1].Highlights["title"] != "Buy <mark>milk</mark> &amp; Milkshakes" {
+		t.Errorf("milk bread: %+v", results)
+	}
+}
+
+func TestApply(t *testing.T) {
+	index := NewIndex()
+	apply := func(eventType string, version int, title string) {
+		index.Apply(events.Event{Type: eventType, Todo: models.Todo{Id: 1, Title: title, Version: version}})
+	}
+	titles := func() []string {
+		titles := []string{}
+		for _, r := range index.Search("title*", 0) {
+			titles = append(titles, r.Todo.Title)
+		}
+		return titles
+	}
+
+	apply(events.Created, 1, "title one")
+	apply(events.Updated, 3, "title three")
+	// late events for older versions are ignored
+	apply(events.Updated, 2, "title two")
+	apply(events.Updated, 3, "title three again")
+	if got := titles(); !reflect.DeepEqual(got, []string{"title three"}) {
+		t.Errorf("titles = %v", got)
+	}
+
+	apply(events.Deleted, 3, "")
+	// the deleted todo doesn't come back with a late update
+	apply(events.Updated, 3, "title three again")
+	apply(events.Updated, 2, "title two")
+	if got := titles(); len(got) != 0 {
+		t.Errorf("titles after delete = %v", got)
+	}
+
+	// a delete arriving before the last update still removes the todo
+	index.Apply(events.Event{Type: events.Created, Todo: models.Todo{Id: 2, Title: "title a", Version: 1}})
+	index.Apply(events.Event{Type: events.Deleted, Todo: models.Todo{Id: 2, Version: 2}})
+	index.Apply(events.Event{Type: events.Updated, Todo: models.Todo{Id: 2, Title: "title b", Version: 2}})
+	if got := titles(); len(got) != 0 {
+		t.Errorf("titles after early delete = %v", got)
+	}
+}
+
+func TestApplyRecreated(t *testing.T) {
+	index := NewIndex()
+	index.Apply(events.Event{Type: events.Created, Todo: models.Todo{Id: 1, Title: "first", Version: 1}})
+	index.Apply(events.Event{Type: events.Updated, Todo: models.Todo{Id: 1, Title: "first", Version: 2}})
+	index.Apply(events.Event{Type: events.Deleted, Todo: models.Todo{Id: 1, Version: 2}})
+
+	// the id is given again, its version starts over
+	index.Apply(events.Event{Type: events.Created, Todo: models.Todo{Id: 1, Title: "second", Version: 1}})
+	if got := index.Search("second", 0); len(got) != 1 || got[0].Todo.Id != 1 {
+		t.Errorf("results = %+v", got)
+	}
+	if len(index.deleted) != 0 {
+		t.Errorf("deleted = %v", index.deleted)
+	}
+
+	index.Apply(events.Event{Type: events.Updated, Todo: models.Todo{Id: 1, Title: "third", Version: 2}})
+	if got := index.Search("third", 0); len(got) != 1 {
+		t.Errorf("update of the new todo: results = %+v", got)
+	}
+}
+
+func TestApplyForgetsOldDeletions(t *testing.T) {
+	index := NewIndex()
+	for id := 1; id <= maxDeleted+10; id++ {
+		index.Apply(events.Event{Type: events.Created, Todo: models.Todo{Id: id, Title: "todo", Version: 1}})
+		index.Apply(events.Event{Type: events.Deleted, Todo: models.Todo{Id: id, Version: 1}})
+	}
+
+	if len(index.deleted) != maxDeleted || len(index.deletedOrder) != maxDeleted {
+		t.Errorf("%v deleted todos remembered, %v in order", len(index.deleted), len(index.deletedOrder))
+	}
+	if _, ok := index.deleted[10]; ok {
+		t.Error("the oldest deletions are still remembered")
+	}
+	if _, ok := index.deleted[maxDeleted+10]; !ok {
+		t.Error("the last deletion is forgotten")
+	}
+}
@@ -0,0 +1,443 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorilla-mux-router/models"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncPolicy decides when the write-ahead log is fsynced
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs after every mutation, nothing acknowledged is lost on a crash
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs every SyncInterval, a crash loses at most that much
+	SyncInterval
+	// SyncNever leaves flushing to the operating system
+	SyncNever
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	}
+	return 0, fmt.Errorf("unknown sync policy %q, expected always, interval or never", s)
+}
+
+type FileStoreOptions struct {
+	// Dir holds the snapshot and the log, it is created if needed
+	Dir          string
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// the log is compacted into a snapshot every CompactInterval, or as soon
+	// as it has CompactThreshold entries
+	CompactInterval  time.Duration
+	CompactThreshold int
+}
+
+const (
+	snapshotFile = "snapshot.json"
+	logFile      = "wal.jsonl"
+
+	defaultSyncInterval     = time.Second
+	defaultCompactInterval  = time.Minute
+	defaultCompactThreshold = 1000
+)
+
+// snapshot is the content of the snapshot file
+type snapshot struct {
+	NextID int           `json:"nextId"`
+	Todos  []models.Todo `json:"todos"`
+}
+
+// logEntry is a line of the write-ahead log. Entries are idempotent (put
+// replaces the whole todo), so replaying a log over a snapshot that already
+// contains some of its entries is safe.
+type logEntry struct {
+	Op   string       `json:"op"` // put or delete
+	Todo *models.Todo `json:"todo,omitempty"`
+	Id   int          `json:"id,omitempty"`
+	// NextID is written with deletes, so the id of a deleted todo is not
+	// given again after a restart
+	NextID int `json:"nextId,omitempty"`
+}
+
+// walFile is the part of *os.File the log is written with
+type walFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// FileStore keeps todos in memory and persists every mutation to a JSON lines
+// write-ahead log, which is periodically compacted into a snapshot
+type FileStore struct {
+	mem  *MemoryStore
+	opts FileStoreOptions
+
+	// mu serialises mutations, so the log has the same order as the memory state
+	mu      sync.Mutex
+	log     walFile
+	entries int
+	dirty   bool // written but not synced yet
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFileStore recovers the state from the snapshot and the log in opts.Dir
+func NewFileStore(opts FileStoreOptions) (*FileStore, error) {
+	if opts.SyncInterval == 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.CompactInterval == 0 {
+		opts.CompactInterval = defaultCompactInterval
+	}
+	if opts.CompactThreshold == 0 {
+		opts.CompactThreshold = defaultCompactThreshold
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	s := &FileStore{mem: NewMemoryStore(), opts: opts, done: make(chan struct{})}
+
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.replayLog(); err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.background()
+
+	return s, nil
+}
+
+func (s *FileStore) List() ([]models.Todo, error) {
+	return s.mem.List()
+}
+
+func (s *FileStore) Get(id int) (models.Todo, error) {
+	return s.mem.Get(id)
+}
+
+func (s *FileStore) Create(todo models.Todo) (models.Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.mem.Create(todo)
+	if err != nil {
+		return models.Todo{}, err
+	}
+
+	if err := s.append(logEntry{Op: "put", Todo: &created}); err != nil {
+		s.mem.remove(created.Id)
+		return models.Todo{}, err
+	}
+	return created, nil
+}
+
+func (s *FileStore) Update(id int, todo models.Todo) (models.Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.mem.Get(id)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	updated, err := s.mem.Update(id, todo)
+	if err != nil {
+		return models.Todo{}, err
+	}
+
+	if err := s.append(logEntry{Op: "put", Todo: &updated}); err != nil {
+		s.mem.put(old)
+		return models.Todo{}, err
+	}
+	return updated, nil
+}
+
+func (s *FileStore) Delete(id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.mem.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.mem.Delete(id, version); err != nil {
+		return err
+	}
+
+	if err := s.append(logEntry{Op: "delete", Id: id, NextID: s.mem.nextId()}); err != nil {
+		s.mem.put(old)
+		return err
+	}
+	return nil
+}
+
+// Close stops the background work, compacts the log and closes it. Calling
+// it again returns the result of the first call.
+func (s *FileStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.closeErr = s.compact()
+		if err := s.log.Close(); s.closeErr == nil {
+			s.closeErr = err
+		}
+	})
+	return s.closeErr
+}
+
+// append writes an entry to the log, must be called with mu held. When the
+// entry can't be written and synced, the log is cut back to where it was,
+// so the caller can undo the mutation in memory.
+func (s *FileStore) append(entry logEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	offset, err := s.log.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	if _, err := s.log.Write(append(line, '\n')); err != nil {
+		return s.rollback(offset, fmt.Errorf("failed to write log: %w", err))
+	}
+	s.dirty = true
+
+	if s.opts.Sync == SyncAlways {
+		if err := s.sync(); err != nil {
+			return s.rollback(offset, err)
+		}
+	}
+	s.entries++
+
+	if s.entries >= s.opts.CompactThreshold {
+		if err := s.compact(); err != nil {
+			// the entry is in the log, the compaction is retried later
+			log.Printf("failed to compact todo log: %v", err)
+		}
+	}
+	return nil
+}
+
+// rollback removes what a failed append wrote after offset, a torn entry
+// would otherwise be followed by the next ones
+func (s *FileStore) rollback(offset int64, err error) error {
+	if truncErr := s.log.Truncate(offset); truncErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to truncate log: %w", truncErr))
+	}
+	if _, seekErr := s.log.Seek(offset, io.SeekStart); seekErr != nil {
+		return errors.Join(err, seekErr)
+	}
+	s.dirty = true
+	return err
+}
+
+func (s *FileStore) sync() error {
+	if !s.dirty {
+		return nil
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// compact writes the current state to the snapshot file and starts a new
+// empty log, must be called with mu held
+func (s *FileStore) compact() error {
+	if s.entries == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(s.mem.state())
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.opts.Dir, snapshotFile), content); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// the snapshot contains every entry of the log, it can be emptied
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.entries = 0
+	s.dirty = true
+	return s.sync()
+}
+
+func (s *FileStore) background() {
+	defer s.wg.Done()
+
+	compactTicker := time.NewTicker(s.opts.CompactInterval)
+	defer compactTicker.Stop()
+
+	var syncTick <-chan time.Time
+	if s.opts.Sync == SyncInterval {
+		syncTicker := time.NewTicker(s.opts.SyncInterval)
+		defer syncTicker.Stop()
+		syncTick = syncTicker.C
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-syncTick:
+			s.mu.Lock()
+			if err := s.sync(); err != nil {
+				log.Println(err)
+			}
+			s.mu.Unlock()
+		case <-compactTicker.C:
+			s.mu.Lock()
+			if err := s.compact(); err != nil {
+				log.Printf("failed to compact todo log: %v", err)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *FileStore) loadSnapshot() error {
+	content, err := os.ReadFile(filepath.Join(s.opts.Dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return fmt.Errorf("corrupt snapshot: %w", err)
+	}
+	s.mem.restore(snap)
+	return nil
+}
+
+// replayLog applies the log on top of the snapshot and opens it for appending.
+// A torn last line (a crash in the middle of a write) is dropped.
+func (s *FileStore) replayLog() error {
+	f, err := os.OpenFile(filepath.Join(s.opts.Dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+
+	var valid int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				log.Printf("dropping incomplete last entry of the todo log")
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to read log: %w", err)
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			f.Close()
+			return fmt.Errorf("corrupt log entry at offset %v: %w", valid, err)
+		}
+
+		switch {
+		case entry.Op == "put" && entry.Todo != nil:
+			s.mem.put(*entry.Todo)
+		case entry.Op == "delete":
+			s.mem.remove(entry.Id)
+			s.mem.reserve(entry.NextID)
+		default:
+			f.Close()
+			return fmt.Errorf("unknown log entry at offset %v: %s", valid, line)
+		}
+
+		valid += int64(len(line))
+		s.entries++
+	}
+
+	// new entries go right after the last complete one
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	s.log = f
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file and renames it, so a crash
+// leaves either the old or the new content
+func writeFileAtomic(path string, content []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// persist the rename itself
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
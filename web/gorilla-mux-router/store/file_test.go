@@ -0,0 +1,229 @@
+package store
+
+import (
+	"errors"
+	"gorilla-mux-router/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func openFileStore(t *testing.T, dir string, opts FileStoreOptions) *FileStore {
+	t.Helper()
+	opts.Dir = dir
+	s, err := NewFileStore(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// crash reopens the data dir of s without closing s, as if the process died
+// after the last acknowledged mutation
+func crash(t *testing.T, s *FileStore) *FileStore {
+	t.Helper()
+	return openFileStore(t, s.opts.Dir, FileStoreOptions{})
+}
+
+func list(t *testing.T, s TodoStore) []models.Todo {
+	t.Helper()
+	todos, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return todos
+}
+
+func TestFileStoreRecovery(t *testing.T) {
+	s := openFileStore(t, t.TempDir(), FileStoreOptions{})
+
+	a, _ := s.Create(models.Todo{Title: "a", Tags: []string{"x"}})
+	b, _ := s.Create(models.Todo{Title: "b"})
+	c, _ := s.Create(models.Todo{Title: "c"})
+	a.Title = "a2"
+	if _, err := s.Update(a.Id, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(b.Id, b.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := crash(t, s)
+	if got, want := list(t, recovered), list(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered %+v, want %+v", got, want)
+	}
+	if todo, _ := recovered.Get(c.Id); todo.Version != 1 || todo.Title != "c" {
+		t.Errorf("c = %+v", todo)
+	}
+}
+
+func TestFileStoreDeletedIdsNotReused(t *testing.T) {
+	s := openFileStore(t, t.TempDir(), FileStoreOptions{})
+
+	s.Create(models.Todo{Title: "a"})
+	last, _ := s.Create(models.Todo{Title: "b"})
+	if err := s.Delete(last.Id, last.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := crash(t, s)
+	created, err := recovered.Create(models.Todo{Title: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Id <= last.Id {
+		t.Errorf("id %v of a deleted todo given again", created.Id)
+	}
+}
+
+func TestFileStoreTornTail(t *testing.T) {
+	s := openFileStore(t, t.TempDir(), FileStoreOptions{})
+	s.Create(models.Todo{Title: "kept"})
+
+	// a crash in the middle of writing the next entry
+	wal, err := os.OpenFile(filepath.Join(s.opts.Dir, logFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.WriteString(`{"op":"put","todo":{"id":2,"tit`)
+	wal.Close()
+
+	recovered := crash(t, s)
+	if todos := list(t, recovered); len(todos) != 1 || todos[0].Title != "kept" {
+		t.Fatalf("todos = %+v", todos)
+	}
+
+	// the torn entry is cut, the next ones are readable
+	recovered.Create(models.Todo{Title: "after"})
+	if todos := list(t, crash(t, recovered)); len(todos) != 2 || todos[1].Title != "after" {
+		t.Errorf("todos = %+v", todos)
+	}
+}
+
+func TestFileStoreCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"op":"put","todo":{"id":1,"title":"a","version":1}}` + "\n" + "not json\n" + `{"op":"delete","id":1}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, logFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the last line can be torn, anything else is corruption
+	if _, err := NewFileStore(FileStoreOptions{Dir: dir}); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	s := openFileStore(t, t.TempDir(), FileStoreOptions{CompactThreshold: 3})
+
+	s.Create(models.Todo{Title: "a"})
+	b, _ := s.Create(models.Todo{Title: "b"})
+	s.Delete(b.Id, b.Version)
+
+	wal, _ := os.ReadFile(filepath.Join(s.opts.Dir, logFile))
+	if len(wal) != 0 {
+		t.Errorf("log not emptied by the compaction: %s", wal)
+	}
+	if _, err := os.Stat(filepath.Join(s.opts.Dir, snapshotFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	// entries after the snapshot are replayed on top of it
+	s.Create(models.Todo{Title: "c"})
+	recovered := crash(t, s)
+	todos := list(t, recovered)
+	if len(todos) != 2 || todos[0].Title != "a" || todos[1].Title != "c" || todos[1].Id != 3 {
+		t.Errorf("todos = %+v", todos)
+	}
+	if created, _ := recovered.Create(models.Todo{Title: "d"}); created.Id != 4 {
+		t.Errorf("id = %v", created.Id)
+	}
+}
+
+func TestFileStoreClose(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(FileStoreOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Create(models.Todo{Title: "a"})
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// a second Close does not panic on the closed channel
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	// Close compacted the log into the snapshot
+	if wal, _ := os.ReadFile(filepath.Join(dir, logFile)); len(wal) != 0 {
+		t.Errorf("log = %s", wal)
+	}
+	if todos := list(t, openFileStore(t, dir, FileStoreOptions{})); len(todos) != 1 {
+		t.Errorf("todos = %+v", todos)
+	}
+}
+
+// faultyLog writes half of the entry then fails, or fails to sync
+type faultyLog struct {
+	*os.File
+	failWrite bool
+	failSync  bool
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (f *faultyLog) Write(p []byte) (int, error) {
+	if f.failWrite {
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, errDiskFull
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultyLog) Sync() error {
+	if f.failSync {
+		return errDiskFull
+	}
+	return f.File.Sync()
+}
+
+func TestFileStoreFailedAppend(t *testing.T) {
+	for _, fault := range []string{"write", "sync"} {
+		t.Run(fault, func(t *testing.T) {
+			s := openFileStore(t, t.TempDir(), FileStoreOptions{})
+			a, _ := s.Create(models.Todo{Title: "a"})
+
+			faulty := &faultyLog{File: s.log.(*os.File), failWrite: fault == "write", failSync: fault == "sync"}
+			s.log = faulty
+
+			if _, err := s.Create(models.Todo{Title: "lost"}); !errors.Is(err, errDiskFull) {
+				t.Errorf("create: err = %v", err)
+			}
+			a.Title = "lost"
+			if _, err := s.Update(a.Id, a); !errors.Is(err, errDiskFull) {
+				t.Errorf("update: err = %v", err)
+			}
+			if err := s.Delete(a.Id, a.Version); !errors.Is(err, errDiskFull) {
+				t.Errorf("delete: err = %v", err)
+			}
+
+			// nothing failed is visible in memory
+			if todos := list(t, s); len(todos) != 1 || todos[0].Title != "a" {
+				t.Errorf("todos = %+v", todos)
+			}
+
+			// nor in the log, which takes new entries once the disk recovers
+			faulty.failWrite, faulty.failSync = false, false
+			s.Create(models.Todo{Title: "b"})
+			todos := list(t, crash(t, s))
+			if len(todos) != 2 || todos[0].Title != "a" || todos[1].Title != "b" {
+				t.Errorf("recovered todos = %+v", todos)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"gorilla-mux-router/models"
+	"sort"
+	"sync"
+)
+
+type MemoryStore struct {
+	mu     sync.RWMutex
+	todos  map[int]models.Todo
+	nextID int
+}
+
+func NewMemoryStore(todos ...models.Todo) *MemoryStore {
+	s := &MemoryStore{todos: map[int]models.Todo{}, nextID: 1}
+	for _, todo := range todos {
+		s.Create(todo)
+	}
+	return s
+}
+
+func (s *MemoryStore) List() ([]models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todos := make([]models.Todo, 0, len(s.todos))
+	for _, todo := range s.todos {
+		todos = append(todos, todo.Clone())
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].Id < todos[j].Id })
+	return todos, nil
+}
+
+func (s *MemoryStore) Get(id int) (models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok {
+		return models.Todo{}, ErrNotFound
+	}
+	return todo.Clone(), nil
+}
+
+func (s *MemoryStore) Create(todo models.Todo) (models.Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if todo.Id == 0 {
+		todo.Id = s.nextID
+	} else if _, ok := s.todos[todo.Id]; ok {
+		return models.Todo{}, ErrConflict
+	}
+	if todo.Id >= s.nextID {
+		s.nextID = todo.Id + 1
+	}
+
+	// the stored todo must not share the tags or the due date with the caller
+	todo.Version = 1
+	s.todos[todo.Id] = todo.Clone()
+	return todo, nil
+}
+
+func (s *MemoryStore) Update(id int, todo models.Todo) (models.Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.todos[id]
+	if !ok {
+		return models.Todo{}, ErrNotFound
+	}
+	if stored.Version != todo.Version {
+		return models.Todo{}, ErrVersionMismatch
+	}
+
+	todo.Id = id
+	todo.Version++
+	s.todos[id] = todo.Clone()
+	return todo, nil
+}
+
+func (s *MemoryStore) Delete(id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.todos[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != version {
+		return ErrVersionMismatch
+	}
+	delete(s.todos, id)
+	return nil
+}
+
+// put inserts or replaces a todo, used to replay the log of a FileStore
+func (s *MemoryStore) put(todo models.Todo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.todos[todo.Id] = todo
+	if todo.Id >= s.nextID {
+		s.nextID = todo.Id + 1
+	}
+}
+
+// remove deletes a todo if it exists, used to replay the log of a FileStore
+func (s *MemoryStore) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.todos, id)
+}
+
+// nextId returns the id the next created todo gets
+func (s *MemoryStore) nextId() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.nextID
+}
+
+// reserve makes sure ids below nextID are not given again, used to replay
+// the log of a FileStore
+func (s *MemoryStore) reserve(nextID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nextID > s.nextID {
+		s.nextID = nextID
+	}
+}
+
+func (s *MemoryStore) state() snapshot {
+	todos, _ := s.List()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return snapshot{NextID: s.nextID, Todos: todos}
+}
+
+func (s *MemoryStore) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.todos = map[int]models.Todo{}
+	for _, todo := range snap.Todos {
+		s.todos[todo.Id] = todo
+	}
+	s.nextID = snap.NextID
+	if s.nextID < 1 {
+		s.nextID = 1
+	}
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"errors"
+	"gorilla-mux-router/models"
+)
+
+var (
+	ErrNotFound = errors.New("todo not found")
+	ErrConflict = errors.New("todo already exists")
+	// ErrVersionMismatch is returned when a todo changed since the version the caller read
+	ErrVersionMismatch = errors.New("todo was modified concurrently")
+)
+
+// TodoStore keeps todos. Implementations must be safe for concurrent use and
+// return copies, so callers can't modify stored todos behind their back.
+//
+// Every todo has a version, starting at 1 and incremented on each update.
+// Update and Delete only apply if the given version is the stored one, which
+// makes read-modify-write cycles safe.
+type TodoStore interface {
+	// List returns all todos ordered by id
+	List() ([]models.Todo, error)
+	Get(id int) (models.Todo, error)
+	// Create assigns the next id when todo.Id is 0 and returns ErrConflict if the id is taken
+	Create(todo models.Todo) (models.Todo, error)
+	// Update replaces the todo with the given id if todo.Version is the stored version
+	Update(id int, todo models.Todo) (models.Todo, error)
+	// Delete removes the todo with the given id if version is the stored version
+	Delete(id int, version int) error
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"errors"
+	"gorilla-mux-router/models"
+	"testing"
+)
+
+// stores returns a constructor for each implementation of TodoStore
+func stores() map[string]func(t *testing.T) TodoStore {
+	return map[string]func(t *testing.T) TodoStore{
+		"memory": func(t *testing.T) TodoStore { return NewMemoryStore() },
+		"file":   func(t *testing.T) TodoStore { return openFileStore(t, t.TempDir(), FileStoreOptions{}) },
+	}
+}
+
+func TestCompareAndSet(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+
+			todo, _ := s.Create(models.Todo{Title: "a", Version: 7})
+			if todo.Version != 1 {
+				t.Errorf("created version = %v", todo.Version)
+			}
+
+			todo.Title = "b"
+			updated, err := s.Update(todo.Id, todo)
+			if err != nil || updated.Version != 2 {
+				t.Fatalf("update: %+v, %v", updated, err)
+			}
+
+			// a writer that read version 1 can neither update nor delete
+			todo.Title = "stale"
+			if _, err := s.Update(todo.Id, todo); !errors.Is(err, ErrVersionMismatch) {
+				t.Errorf("stale update: err = %v", err)
+			}
+			if err := s.Delete(todo.Id, 1); !errors.Is(err, ErrVersionMismatch) {
+				t.Errorf("stale delete: err = %v", err)
+			}
+			if got, _ := s.Get(todo.Id); got.Title != "b" {
+				t.Errorf("title = %q", got.Title)
+			}
+
+			if err := s.Delete(todo.Id, 2); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Update(todo.Id, updated); !errors.Is(err, ErrNotFound) {
+				t.Errorf("update after delete: err = %v", err)
+			}
+			if err := s.Delete(todo.Id, 2); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second delete: err = %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateConflict(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+
+			if _, err := s.Create(models.Todo{Id: 5, Title: "a"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Create(models.Todo{Id: 5, Title: "b"}); !errors.Is(err, ErrConflict) {
+				t.Errorf("err = %v", err)
+			}
+			// ids continue after the highest one given
+			if todo, _ := s.Create(models.Todo{Title: "c"}); todo.Id != 6 {
+				t.Errorf("id = %v", todo.Id)
+			}
+		})
+	}
+}
+
+func TestReturnsCopies(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+
+			tags := []string{"a"}
+			todo, _ := s.Create(models.Todo{Title: "a", Tags: tags})
+			tags[0] = "changed"
+			todo.Tags[0] = "changed"
+
+			got, _ := s.Get(todo.Id)
+			got.Tags[0] = "changed"
+			if got, _ := s.Get(todo.Id); got.Tags[0] != "a" {
+				t.Errorf("tags = %v", got.Tags)
+			}
+		})
+	}
+}
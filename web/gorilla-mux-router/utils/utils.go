@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"log"
+	"net/http"
 )
 
 func Json(data interface{}) ([]byte, error) {
@@ -13,3 +14,21 @@ func Json(data interface{}) ([]byte, error) {
 	}
 	return content, nil
 }
+
+// WriteJson sends data as a json response with the given status
+func WriteJson(w http.ResponseWriter, status int, data interface{}) {
+	content, err := Json(data)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+// WriteError sends {"error": message} with the given status
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJson(w, status, map[string]string{"error": message})
+}
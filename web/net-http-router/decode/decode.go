@@ -0,0 +1,116 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net-http-router/problem"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const DefaultMaxBytes = 1 << 20
+
+// JSON decodes the request body into v with DefaultMaxBytes, see JSONLimit
+func JSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return JSONLimit(w, r, v, DefaultMaxBytes)
+}
+
+// JSONLimit strictly decodes the request body into v: the content type must
+// be json, the body a single json value of at most maxBytes, without fields
+// unknown to v. Errors are *problem.Problem, naming the field at fault and
+// the offset of the first byte of the offending token when known.
+func JSONLimit(w http.ResponseWriter, r *http.Request, v interface{}, maxBytes int64) error {
+	if err := checkContentType(r); err != nil {
+		return err
+	}
+
+	// the body is read first so errors can point anywhere in it
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return problem.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("body must not be larger than %v bytes", maxBytes))
+		}
+		return problem.New(http.StatusBadRequest, "failed to read the body")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(v); err != nil {
+		return decodeProblem(err, body)
+	}
+
+	// anything but whitespace after the value is rejected
+	rest := bytes.TrimLeft(body[decoder.InputOffset():], " \t\r\n")
+	if len(rest) > 0 {
+		return problem.New(http.StatusBadRequest, "body must contain a single JSON value").At(int64(len(body) - len(rest)))
+	}
+
+	// encoding/json reports unknown fields without their path or offset
+	if field, offset, ok := unknownField(body, reflect.TypeOf(v)); ok {
+		p := problem.New(http.StatusBadRequest, fmt.Sprintf("unknown field %q", field)).At(offset)
+		p.Field = field
+		return p
+	}
+	return nil
+}
+
+func checkContentType(r *http.Request) error {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return problem.New(http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return problem.New(http.StatusUnsupportedMediaType, fmt.Sprintf("Content-Type must be application/json, got %q", header))
+	}
+	return nil
+}
+
+func decodeProblem(err error, body []byte) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, "body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return problem.New(http.StatusBadRequest, "body contains incomplete JSON").At(int64(len(body)))
+	case errors.As(err, &syntaxErr):
+		return problem.New(http.StatusBadRequest, fmt.Sprintf("body contains malformed JSON: %v", syntaxErr)).At(syntaxErr.Offset - 1)
+	case errors.As(err, &typeErr):
+		p := problem.New(http.StatusBadRequest, fmt.Sprintf("expected %v, got a JSON %v", typeErr.Type, typeErr.Value)).At(tokenStart(body, typeErr.Offset))
+		p.Field = typeErr.Field
+		if p.Field == "" {
+			p.Detail = fmt.Sprintf("body must be a JSON %v, got a JSON %v", jsonKind(typeErr.Type.Kind()), typeErr.Value)
+		}
+		return p
+	}
+	return problem.New(http.StatusBadRequest, err.Error())
+}
+
+// tokenStart returns the offset of the first byte of the token ending at
+// end, encoding/json reports the end of the values it can't decode
+func tokenStart(body []byte, end int64) int64 {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for {
+		start := decoder.InputOffset()
+		if _, err := decoder.Token(); err != nil || decoder.InputOffset() >= end {
+			return start + int64(len(body[start:])-len(bytes.TrimLeft(body[start:], ",: \t\r\n")))
+		}
+	}
+}
+
+func jsonKind(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return kind.String()
+}
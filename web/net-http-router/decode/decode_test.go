@@ -0,0 +1,93 @@
+package decode
+
+import (
+	"errors"
+	"net-http-router/problem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type message struct {
+	Title string `json:"title"`
+	Meta  struct {
+		Tags []string `json:"tags"`
+	} `json:"meta"`
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	Labels map[string]string `json:"labels"`
+	Extra  interface{}       `json:"extra"`
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+		offset int64
+	}{
+		{"valid", `{"title":"a","meta":{"tags":["b"]}} `, 0, "", 0},
+		{"unknown field", `{"title":"a",  "titel":"b"}`, 400, "titel", 15},
+		{"nested unknown field", `{"meta":{"tags":[],"size":1}}`, 400, "meta.size", 19},
+		{"unknown field named like a known one", `{"title":"a","meta":{"title":"x"}}`, 400, "meta.title", 21},
+		{"unknown field in an array", `{"items":[{"name":"a"},{"Name":"b","size":1}]}`, 400, "items.1.size", 35},
+		{"maps and interfaces take any key", `{"labels":{"a":"b"},"extra":{"c":1}}`, 0, "", 0},
+		{"wrong type", `{"title": 1}`, 400, "title", 10},
+		{"nested wrong type", `{"meta":{"tags":[1]}}`, 400, "meta.tags.0", 17},
+		{"malformed", `{"title" "a"}`, 400, "", 9},
+		{"object for a string", `{"title":{"a":1}}`, 400, "title", 9},
+		{"incomplete", `{"title":"a"`, 400, "", 12},
+		{"trailing data", `{"title":"a"} {}`, 400, "", 14},
+		{"empty", ``, 400, "", -1},
+		{"too large", `{"title":"` + strings.Repeat("a", 100) + `"}`, 413, "", -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+			var msg message
+			err := JSONLimit(httptest.NewRecorder(), r, &msg, 64)
+			if test.status == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var p *problem.Problem
+			if !errors.As(err, &p) {
+				t.Fatalf("error = %v, want a *problem.Problem", err)
+			}
+			if p.Status != test.status || p.Field != test.field {
+				t.Errorf("status, field = %v, %q, want %v, %q", p.Status, p.Field, test.status, test.field)
+			}
+			if test.offset >= 0 && (p.Offset == nil || *p.Offset != test.offset) {
+				t.Errorf("offset = %v, want %v", offset(p), test.offset)
+			}
+		})
+	}
+}
+
+func TestJSONContentType(t *testing.T) {
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		r.Header.Set("Content-Type", contentType)
+
+		var p *problem.Problem
+		if err := JSON(httptest.NewRecorder(), r, &message{}); !errors.As(err, &p) || p.Status != http.StatusUnsupportedMediaType {
+			t.Errorf("Content-Type %q: error = %v, want a 415", contentType, err)
+		}
+	}
+}
+
+func offset(p *problem.Problem) interface{} {
+	if p.Offset == nil {
+		return nil
+	}
+	return *p.Offset
+}
@@ -0,0 +1,211 @@
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"net-http-router/problem"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Params binds the path values and query parameters of r into the struct
+// pointed to by v, using the tags of its fields:
+//
+//	type listRequest struct {
+//		Id     int       `path:"id"`
+//		Name   string    `query:"name,required"` // ?name= is missing too
+//		Tags   []string  `query:"tag"` // ?tag=a&tag=b or ?tag=a,b
+//		Sort   string    `query:"sort" default:"asc" enum:"asc,desc"`
+//		Since  time.Time `query:"since"` // RFC 3339 or 2006-01-02
+//		Expand bool      `query:"expand"`
+//		Limit  int       `query:",required"` // ?Limit=, named after the field
+//	}
+//
+// Every invalid parameter is reported at once in a 400 *problem.Problem.
+func Params(r *http.Request, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode: Params needs a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	query := r.URL.Query()
+
+	var invalid []problem.InvalidParam
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+
+		pathTag, queryTag := field.Tag.Get("path"), field.Tag.Get("query")
+		if pathTag == "" && queryTag == "" || !field.IsExported() {
+			continue
+		}
+		if pathTag != "" && queryTag != "" {
+			return fmt.Errorf("decode: field %v has both a path and a query tag", field.Name)
+		}
+		in, tag := "path", pathTag
+		if queryTag != "" {
+			in, tag = "query", queryTag
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			// `query:",required"` names the parameter after the field, as encoding/json does
+			name = field.Name
+		}
+		required := false
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case "":
+			case "required":
+				required = true
+			default:
+				return fmt.Errorf("decode: field %v: unknown option %q", field.Name, option)
+			}
+		}
+
+		var values []string
+		if in == "path" {
+			values = []string{r.PathValue(name)}
+		} else {
+			values = query[name]
+		}
+
+		// ?name= is no more a value than a missing parameter
+		if empty(values) {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				values = []string{def}
+			} else if required {
+				invalid = append(invalid, problem.InvalidParam{Name: name, In: in, Reason: "is required"})
+				continue
+			} else {
+				continue
+			}
+		}
+
+		if err := setParam(rv.Field(i), values, field.Tag.Get("enum")); err != nil {
+			var unsupported *unsupportedError
+			if errors.As(err, &unsupported) {
+				return fmt.Errorf("decode: field %v: %w", field.Name, err)
+			}
+			invalid = append(invalid, problem.InvalidParam{Name: name, In: in, Reason: err.Error()})
+		}
+	}
+
+	if len(invalid) > 0 {
+		p := problem.New(http.StatusBadRequest, "invalid parameters")
+		p.InvalidParams = invalid
+		return p
+	}
+	return nil
+}
+
+type unsupportedError struct {
+	t reflect.Type
+}
+
+func (e *unsupportedError) Error() string {
+	return fmt.Sprintf("unsupported type %v", e.t)
+}
+
+// setParam converts values to the type of v, slices take every value and
+// split them on commas, other types take the first value
+func setParam(v reflect.Value, values []string, enum string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var items []string
+		for _, value := range values {
+			items = append(items, strings.Split(value, ",")...)
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setScalar(slice.Index(i), item, enum); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setScalar(v, values[0], enum)
+}
+
+func setScalar(v reflect.Value, raw string, enum string) error {
+	if enum != "" && !contains(strings.Split(enum, ","), raw) {
+		return fmt.Errorf("must be one of %v", strings.ReplaceAll(enum, ",", ", "))
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, raw); err != nil {
+				return fmt.Errorf("expected a date (2006-01-02) or an RFC 3339 time")
+			}
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 1h30m")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(f)
+	default:
+		return &unsupportedError{v.Type()}
+	}
+	return nil
+}
+
+func empty(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package decode
+
+import (
+	"errors"
+	"net-http-router/problem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type listRequest struct {
+	Id      int           `path:"id"`
+	Name    string        `query:"name,required"`
+	Tags    []string      `query:"tag"`
+	Ids     []int         `query:"ids"`
+	Sort    string        `query:"sort" default:"asc" enum:"asc,desc"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout" default:"5s"`
+	Expand  bool          `query:"expand"`
+}
+
+// newRequest serves url with a mux so the path values are set
+func newRequest(t *testing.T, url string) *http.Request {
+	var req *http.Request
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) { req = r })
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	if req == nil {
+		t.Fatalf("%v did not match", url)
+	}
+	return req
+}
+
+func TestParams(t *testing.T) {
+	var got listRequest
+	err := Params(newRequest(t, "/items/7?name=go&tag=a&tag=b,c&ids=1,2&since=2026-01-02&expand=true"), &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := listRequest{
+		Id:      7,
+		Name:    "go",
+		Tags:    []string{"a", "b", "c"},
+		Ids:     []int{1, 2},
+		Sort:    "asc",
+		Since:   time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Timeout: 5 * time.Second,
+		Expand:  true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParamsErrors(t *testing.T) {
+	err := Params(newRequest(t, "/items/x?ids=1,a&sort=up&since=yesterday&expand=maybe"), &listRequest{})
+
+	var p *problem.Problem
+	if !errors.As(err, &p) || p.Status != http.StatusBadRequest {
+		t.Fatalf("error = %v, want a 400 problem", err)
+	}
+
+	want := map[string]string{"id": "path", "name": "query", "ids": "query", "sort": "query", "since": "query", "expand": "query"}
+	if len(p.InvalidParams) != len(want) {
+		t.Errorf("invalid params = %+v, want %v", p.InvalidParams, want)
+	}
+	for _, param := range p.InvalidParams {
+		if want[param.Name] != param.In {
+			t.Errorf("unexpected invalid param %+v", param)
+		}
+	}
+}
+
+func TestParamsUnsupported(t *testing.T) {
+	var req struct {
+		Filter map[string]string `query:"filter"`
+	}
+	err := Params(newRequest(t, "/items/1?filter=a"), &req)
+
+	var p *problem.Problem
+	if err == nil || errors.As(err, &p) {
+		t.Errorf("error = %v, want a programming error", err)
+	}
+}
+
+func TestParamsRequired(t *testing.T) {
+	var req struct {
+		Name  string `query:"name,required"`
+		Owner string `query:"owner,required,"`
+		Page  int    `query:"page" default:"1"`
+	}
+	err := Params(newRequest(t, "/items/1?name=&owner=&page="), &req)
+
+	var p *problem.Problem
+	if !errors.As(err, &p) || len(p.InvalidParams) != 2 {
+		t.Fatalf("error = %v, want name and owner to be required", err)
+	}
+	if req.Page != 1 {
+		t.Errorf("page = %v, want the default", req.Page)
+	}
+}
+
+func TestParamsBadTags(t *testing.T) {
+	tests := map[string]interface{}{
+		"path and query": &struct {
+			Id int `path:"id" query:"id"`
+		}{},
+		"unknown option": &struct {
+			Name string `query:"name,requird"`
+		}{},
+	}
+	for name, v := range tests {
+		err := Params(newRequest(t, "/items/1?name=a"), v)
+
+		var p *problem.Problem
+		if err == nil || errors.As(err, &p) {
+			t.Errorf("%v: error = %v, want a programming error", name, err)
+		}
+	}
+}
+
+func TestParamsFieldName(t *testing.T) {
+	var query struct {
+		Limit int `query:",required"`
+	}
+	if err := Params(newRequest(t, "/items/1?Limit=5&limit=9"), &query); err != nil || query.Limit != 5 {
+		t.Errorf("limit = %v, error %v", query.Limit, err)
+	}
+
+	err := Params(newRequest(t, "/items/1"), &query)
+	var p *problem.Problem
+	if !errors.As(err, &p) || len(p.InvalidParams) != 1 || p.InvalidParams[0].Name != "Limit" {
+		t.Errorf("error = %v, want Limit to be required", err)
+	}
+}
@@ -0,0 +1,160 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownField walks a valid json body along the type it was decoded into
+// and returns the path (eg: meta.tags.0.name, like json.UnmarshalTypeError)
+// and the offset of the first object key that type has no field for
+func unknownField(body []byte, t reflect.Type) (string, int64, bool) {
+	w := &walker{body: body, decoder: json.NewDecoder(bytes.NewReader(body))}
+	w.value(t, "")
+	return w.field, w.offset, w.found
+}
+
+type walker struct {
+	body    []byte
+	decoder *json.Decoder
+
+	found  bool
+	field  string
+	offset int64
+}
+
+// value consumes the next value, stopping at the first unknown field
+func (w *walker) value(t reflect.Type, path string) {
+	token, err := w.decoder.Token()
+	if err != nil {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// custom decoders and interfaces accept any object
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType) {
+		w.skip(token)
+		return
+	}
+
+	switch token {
+	case json.Delim('{'):
+		switch {
+		case t.Kind() == reflect.Map:
+			w.object(path, func(string) (reflect.Type, bool) { return t.Elem(), true })
+		case t.Kind() == reflect.Struct:
+			w.object(path, func(key string) (reflect.Type, bool) { return fieldType(t, key) })
+		default:
+			w.skip(token)
+		}
+	case json.Delim('['):
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			w.skip(token)
+			return
+		}
+		for i := 0; w.decoder.More() && !w.found; i++ {
+			w.value(t.Elem(), join(path, strconv.Itoa(i)))
+		}
+		w.decoder.Token()
+	}
+}
+
+func (w *walker) object(path string, field func(key string) (reflect.Type, bool)) {
+	for w.decoder.More() && !w.found {
+		start := w.decoder.InputOffset()
+		token, err := w.decoder.Token()
+		if err != nil {
+			return
+		}
+		key, _ := token.(string)
+
+		t, ok := field(key)
+		if !ok {
+			w.found, w.field = true, join(path, key)
+			w.offset = start + int64(len(w.body[start:])-len(bytes.TrimLeft(w.body[start:], ", \t\r\n")))
+			return
+		}
+		w.value(t, join(path, key))
+	}
+	w.decoder.Token()
+}
+
+// skip consumes the rest of a value whose first token was read
+func (w *walker) skip(token json.Token) {
+	if token != json.Delim('{') && token != json.Delim('[') {
+		return
+	}
+	for depth := 1; depth > 0; {
+		token, err := w.decoder.Token()
+		if err != nil {
+			return
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// fieldType returns the type of the field of struct t that encoding/json
+// decodes key into: exact names first, then case insensitive ones
+func fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	var folded reflect.Type
+	var match func(t reflect.Type) reflect.Type
+	match = func(t reflect.Type) reflect.Type {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+
+			// embedded structs without a name have their fields promoted
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				if found := match(ft); found != nil {
+					return found
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = f.Name
+			}
+			if name == key {
+				return f.Type
+			}
+			if folded == nil && strings.EqualFold(name, key) {
+				folded = f.Type
+			}
+		}
+		return nil
+	}
+
+	if found := match(t); found != nil {
+		return found, true
+	}
+	return folded, folded != nil
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
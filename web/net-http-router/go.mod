@@ -1,3 +1,15 @@
 module net-http-router
 
 go 1.23.4
+
+require (
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/net v0.38.0
+)
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
@@ -1,16 +1,27 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
+	"net-http-router/decode"
+	"net-http-router/problem"
+	"net-http-router/render"
 	"net/http"
 )
 
+type pathParamRequest struct {
+	Id   int    `path:"id"`
+	Name string `query:"name" default:"world"`
+}
+
 func PathParamHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-	name := r.URL.Query().Get("name")
-	json.NewEncoder(w).Encode(map[string]string{
-		"id":   id,
-		"name": fmt.Sprintf("hello %v", name),
+	var req pathParamRequest
+	if err := decode.Params(r, &req); err != nil {
+		problem.Write(w, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"id":   req.Id,
+		"name": fmt.Sprintf("hello %v", req.Name),
 	})
 }
@@ -1,17 +1,26 @@
 package handlers
 
 import (
-	"encoding/json"
+	"encoding/xml"
+	"net-http-router/decode"
 	"net-http-router/models"
+	"net-http-router/problem"
+	"net-http-router/render"
 	"net/http"
 )
 
+type messageResponse struct {
+	XMLName xml.Name       `json:"-" xml:"response"`
+	Data    models.Message `json:"data" xml:"data"`
+}
+
 func PostBodyHandler(w http.ResponseWriter, r *http.Request) {
 	var msg models.Message
 
-	json.NewDecoder(r.Body).Decode(&msg)
+	if err := decode.JSON(w, r, &msg); err != nil {
+		problem.Write(w, err)
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]models.Message{
-		"data": msg,
-	})
+	render.Render(w, r, http.StatusOK, messageResponse{Data: msg})
 }
@@ -1,14 +1,25 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
+	"net-http-router/decode"
+	"net-http-router/problem"
+	"net-http-router/render"
 	"net/http"
 )
 
+type queryParamsRequest struct {
+	Name string `query:"name,required"`
+}
+
 func QueryParamsHandler(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": fmt.Sprintf("hello %v", name),
+	var req queryParamsRequest
+	if err := decode.Params(r, &req); err != nil {
+		problem.Write(w, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]string{
+		"message": fmt.Sprintf("hello %v", req.Name),
 	})
 }
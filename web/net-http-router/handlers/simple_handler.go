@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"encoding/json"
+	"net-http-router/render"
 	"net/http"
 )
 
 func SimpleHandler(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(map[string]string{
+	render.JSON(w, http.StatusOK, map[string]string{
 		"message": "hello world",
 	})
 }
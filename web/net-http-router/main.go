@@ -1,11 +1,38 @@
 package main
 
 import (
+	"flag"
+	"log"
 	"net-http-router/routes"
+	"net-http-router/server"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 func main() {
+	var opts server.Options
+	flag.StringVar(&opts.Addr, "addr", ":8080", "address of the plain http server")
+	flag.StringVar(&opts.TLSAddr, "tls-addr", ":8443", "address of the https server, when tls is enabled")
+	flag.StringVar(&opts.CertFile, "cert", "", "tls certificate file")
+	flag.StringVar(&opts.KeyFile, "key", "", "tls key file")
+	dev := flag.Bool("dev", false, "serve https with a self-signed certificate cached in the user cache directory")
+	devHosts := flag.String("dev-hosts", "", "comma separated names the dev certificate is valid for, besides localhost")
+	flag.BoolVar(&opts.RedirectHTTP, "redirect", false, "redirect plain http requests to https")
+	flag.BoolVar(&opts.H2C, "h2c", false, "serve http/2 without tls on the plain http server")
+	debug := flag.Bool("debug-routes", false, "serve the route table at /debug/routes, not for production")
+	flag.Parse()
 
-	routes.Serve()
+	if *dev {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.DevCertDir = filepath.Join(cacheDir, "net-http-router", "certs")
+		if *devHosts != "" {
+			opts.DevHosts = strings.Split(*devHosts, ",")
+		}
+	}
 
+	routes.Serve(opts, *debug)
 }
@@ -1,6 +1,6 @@
 package models
 
 type Message struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
+	Title string `json:"title" xml:"title"`
+	Body  string `json:"body" xml:"body"`
 }
@@ -0,0 +1,58 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object, extended with the request
+// field at fault and the byte offset of the error in the body
+type Problem struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Offset *int64 `json:"offset,omitempty"`
+	// InvalidParams lists every invalid path or query parameter
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	In     string `json:"in"` // path or query
+	Reason string `json:"reason"`
+}
+
+func New(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// At sets the byte offset of the error in the request body
+func (p *Problem) At(offset int64) *Problem {
+	p.Offset = &offset
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// Write sends err as problem+json, errors that are not a *Problem become a
+// 500 without details
+func Write(w http.ResponseWriter, err error) {
+	var p *Problem
+	if !errors.As(err, &p) {
+		p = New(http.StatusInternalServerError, "")
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is an entry of an Accept header, eg: application/*;q=0.8
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// specificity ranks */* below type/* below type/subtype
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	if m.mediaType == "*/*" || m.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(m.mediaType, "*")
+	return ok && strings.HasPrefix(mediaType, prefix)
+}
+
+// parseAccept reads the media ranges of an Accept header, ignoring the
+// malformed ones. An empty header accepts anything.
+func parseAccept(header string) []mediaRange {
+	if strings.TrimSpace(header) == "" {
+		return []mediaRange{{mediaType: "*/*", q: 1}}
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	// the most specific range decides the quality of a media type
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// quality returns the q-value given to mediaType, 0 if it is not acceptable
+func quality(ranges []mediaRange, mediaType string) float64 {
+	for _, r := range ranges {
+		if r.matches(mediaType) {
+			return r.q
+		}
+	}
+	return 0
+}
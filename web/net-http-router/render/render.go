@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net-http-router/problem"
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format encodes responses in a media type, Aliases are accepted from
+// clients but the response is sent with MediaType
+type Format struct {
+	MediaType string
+	Aliases   []string
+	Encode    func(v interface{}) ([]byte, error)
+}
+
+// Formats are offered in order, the first one wins when the client accepts
+// several with the same quality
+var Formats = []Format{
+	{MediaType: "application/json", Encode: json.Marshal},
+	{MediaType: "application/xml", Aliases: []string{"text/xml"}, Encode: encodeXML},
+	{MediaType: "application/msgpack", Aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, Encode: encodeMsgpack},
+	{MediaType: "application/cbor", Encode: cbor.Marshal},
+}
+
+// Render encodes v in the format preferred by the Accept header of r, with
+// a 406 problem if none of Formats is acceptable. XML uses the xml tags of
+// v, the binary formats reuse its json tags.
+func Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Add("Vary", "Accept")
+
+	format, ok := Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		problem.Write(w, problem.New(http.StatusNotAcceptable, fmt.Sprintf("supported media types are %v", supported())))
+		return
+	}
+
+	content, err := format.Encode(v)
+	if err != nil {
+		log.Printf("error while encoding %v : %v", format.MediaType, err)
+		problem.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.MediaType)
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+// JSON sends v as json, for responses that are not negotiated
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error while encoding json : %v", err)
+		problem.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+// Negotiate returns the format with the highest quality in the Accept header
+func Negotiate(accept string) (Format, bool) {
+	ranges := parseAccept(accept)
+
+	best, bestQ := Format{}, 0.0
+	for _, format := range Formats {
+		for _, mediaType := range append([]string{format.MediaType}, format.Aliases...) {
+			if q := quality(ranges, mediaType); q > bestQ {
+				best, bestQ = format, q
+			}
+		}
+	}
+	return best, bestQ > 0
+}
+
+func supported() string {
+	var buf bytes.Buffer
+	for i, format := range Formats {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(format.MediaType)
+	}
+	return buf.String()
+}
+
+func encodeXML(v interface{}) ([]byte, error) {
+	content, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+func encodeMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
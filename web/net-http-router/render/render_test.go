@@ -0,0 +1,77 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string // empty when nothing is acceptable
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/xml", "application/xml"},
+		{"text/*", "application/xml"},
+		{"application/x-msgpack", "application/msgpack"},
+		{"application/cbor;q=0.9, application/msgpack", "application/msgpack"},
+		{"application/cbor, application/msgpack", "application/msgpack"},
+		{"application/json;q=0, application/*;q=0.5", "application/xml"},
+		{"application/json;q=0.2, application/cbor;q=0.8", "application/cbor"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+		{"application/json;q=2", ""},
+	}
+
+	for _, test := range tests {
+		format, ok := Negotiate(test.accept)
+		if got := format.MediaType; ok != (test.want != "") || got != test.want {
+			t.Errorf("Negotiate(%q) = %q, %v, want %q", test.accept, got, ok, test.want)
+		}
+	}
+}
+
+type message struct {
+	Title string `json:"title" xml:"title"`
+}
+
+func TestRenderBinaryFormats(t *testing.T) {
+	unmarshal := map[string]func([]byte, interface{}) error{
+		"application/msgpack": msgpack.Unmarshal,
+		"application/cbor":    cbor.Unmarshal,
+	}
+
+	for mediaType, decode := range unmarshal {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", mediaType)
+		w := httptest.NewRecorder()
+		Render(w, r, http.StatusCreated, message{Title: "hello"})
+
+		if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != mediaType {
+			t.Fatalf("%v: got %v %v", mediaType, w.Code, w.Header().Get("Content-Type"))
+		}
+
+		// the json tags are used
+		var got map[string]interface{}
+		if err := decode(w.Body.Bytes(), &got); err != nil || got["title"] != "hello" {
+			t.Errorf("%v: decoded %v, %v", mediaType, got, err)
+		}
+	}
+}
+
+func TestRenderNotAcceptable(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	Render(w, r, http.StatusOK, message{})
+
+	if w.Code != http.StatusNotAcceptable || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("got %v, Vary %q", w.Code, w.Header().Get("Vary"))
+	}
+}
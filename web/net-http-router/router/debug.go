@@ -0,0 +1,47 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+)
+
+// DebugRoutes serves the route table as json, or as a text table when
+// ?format=text is set or the client accepts text/plain but not json
+func (r *Router) DebugRoutes(w http.ResponseWriter, req *http.Request) {
+	routes := r.Routes()
+
+	accept := req.Header.Get("Accept")
+	text := req.URL.Query().Get("format") == "text" ||
+		(strings.Contains(accept, "text/plain") && !strings.Contains(accept, "json"))
+	if !text {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routes)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "METHOD\tPATH\tMOUNT\tMIDDLEWARES\tHANDLER")
+	for _, route := range routes {
+		method := route.Method
+		if method == "" {
+			method = "*"
+		}
+		path := route.Host + route.Path
+		if route.Deprecated {
+			path += " (deprecated)"
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\n", method, path, dash(route.Mount), dash(strings.Join(route.Middlewares, " > ")), route.Handler)
+	}
+	table.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
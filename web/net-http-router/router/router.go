@@ -0,0 +1,125 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Middleware func(http.Handler) http.Handler
+
+// Router is an http.ServeMux on which sub routers can be mounted under a
+// prefix, eg: one per api version. Every registration is recorded, see Routes.
+type Router struct {
+	root *SubRouter
+}
+
+func New() *Router {
+	return &Router{root: &SubRouter{mux: http.NewServeMux(), mounts: map[string]*SubRouter{}}}
+}
+
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.root.Handle(pattern, handler)
+}
+
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	r.root.HandleFunc(pattern, handler)
+}
+
+// Mount serves every path under prefix with a new sub router, see SubRouter.Mount
+func (r *Router) Mount(prefix string, mws ...Middleware) *SubRouter {
+	return r.root.Mount(prefix, mws...)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.root.mux.ServeHTTP(w, req)
+}
+
+type SubRouter struct {
+	parent      *SubRouter
+	prefix      string
+	mux         *http.ServeMux
+	mounts      map[string]*SubRouter // by mux pattern, eg: "/api/"
+	routes      []route
+	middlewares []Middleware
+	deprecation *Deprecation
+}
+
+// Deprecation describes an api version that clients should move away from
+type Deprecation struct {
+	// Since is when the version was deprecated, it is sent in the
+	// Deprecation header (RFC 9745)
+	Since time.Time
+	// Sunset is when the version stops answering, sent in the Sunset
+	// header (RFC 8594) if set
+	Sunset time.Time
+	// Successor is the prefix of the version replacing this one, linked
+	// with rel="successor-version" if set
+	Successor string
+}
+
+// Mount serves every path under prefix with a new sub router. Its patterns
+// are relative to the prefix: "GET /demo" mounted at "/api/v1" answers
+// GET /api/v1/demo. mws only run for requests under the prefix.
+func (s *SubRouter) Mount(prefix string, mws ...Middleware) *SubRouter {
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "{} ") {
+		panic(fmt.Sprintf("router: invalid mount prefix %q", prefix))
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	sub := &SubRouter{parent: s, prefix: prefix, mux: http.NewServeMux(), mounts: map[string]*SubRouter{}, middlewares: mws}
+	s.mux.Handle(prefix+"/", http.StripPrefix(prefix, sub))
+	s.mounts[prefix+"/"] = sub
+	return sub
+}
+
+// Use appends middlewares, they run in the order they are added
+func (s *SubRouter) Use(mws ...Middleware) {
+	s.middlewares = append(s.middlewares, mws...)
+}
+
+// Deprecate adds the deprecation headers to every response under the prefix
+func (s *SubRouter) Deprecate(d Deprecation) {
+	s.deprecation = &d
+}
+
+func (s *SubRouter) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+	s.routes = append(s.routes, newRoute(pattern, funcName(handler)))
+}
+
+func (s *SubRouter) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+	s.routes = append(s.routes, newRoute(pattern, funcName(handler)))
+}
+
+// Prefix returns the full path the sub router is mounted at
+func (s *SubRouter) Prefix() string {
+	if s.parent == nil {
+		return s.prefix
+	}
+	return s.parent.Prefix() + s.prefix
+}
+
+func (s *SubRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.deprecation != nil {
+		s.deprecation.setHeaders(w.Header())
+	}
+
+	var handler http.Handler = s.mux
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		handler = s.middlewares[i](handler)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func (d *Deprecation) setHeaders(header http.Header) {
+	header.Set("Deprecation", fmt.Sprintf("@%v", d.Since.Unix()))
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		header.Add("Link", fmt.Sprintf(`<%v>; rel="successor-version"`, d.Successor))
+	}
+}
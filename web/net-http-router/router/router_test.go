@@ -0,0 +1,188 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	r := New()
+	api := r.Mount("/api/", record("mount"))
+	api.Use(record("use"))
+	api.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler:"+r.URL.Path)
+	})
+	r.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "root")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/ping", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+
+	want := []string{"mount", "use", "handler:/ping", "root"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	r := New()
+	v1 := r.Mount("/v1")
+	v1.Deprecate(Deprecation{
+		Since:     time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2",
+	})
+	v1.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/ping", nil))
+
+	headers := map[string]string{
+		"Deprecation": "@1767225600",
+		"Sunset":      "Wed, 30 Jun 2027 00:00:00 GMT",
+		"Link":        `</v2>; rel="successor-version"`,
+	}
+	for name, want := range headers {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%v = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestInvalidPrefix(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Mount did not panic on a relative prefix")
+		}
+	}()
+	New().Mount("api")
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {}
+
+func noop(next http.Handler) http.Handler { return next }
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.HandleFunc("GET /health", ok)
+	api := r.Mount("/api", noop)
+	v1 := api.Mount("/v1")
+	v1.Deprecate(Deprecation{})
+	v1.Use(noop)
+	v1.HandleFunc("POST /items/{id}", ok)
+
+	want := []Route{
+		{Method: "POST", Path: "/api/v1/items/{id}", Mount: "/api/v1", Middlewares: []string{"router.noop", "router.noop"}, Deprecated: true, Handler: "router.ok"},
+		{Method: "GET", Path: "/health", Handler: "router.ok"},
+	}
+	if got := r.Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	r := New()
+	r.HandleFunc("GET /api/v1/items", ok)
+	api := r.Mount("/api")
+	api.HandleFunc("GET /v1/items/{id}", ok)
+	api.HandleFunc("GET /v2/items", ok)
+	api.HandleFunc("GET /v1/search", ok)
+	v1 := r.Mount("/api/v1")
+	v1.HandleFunc("GET /items", ok)
+	v1.HandleFunc("GET /items/{id}", ok)
+
+	err := r.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted shadowed routes")
+	}
+
+	want := []string{
+		"route GET /api/v1/items/{id} (mounted at /api) is shadowed by GET /api/v1/items/{id} (mounted at /api/v1)",
+		"route GET /api/v1/search (mounted at /api) is shadowed by the mount /api/v1",
+		"route GET /api/v1/items (mounted at /api/v1) is shadowed by GET /api/v1/items",
+	}
+	got := strings.Split(err.Error(), "\n")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+}
+
+func TestHostRoutes(t *testing.T) {
+	r := New()
+	r.HandleFunc("GET /items", ok)
+	r.HandleFunc("GET example.com/items", ok)
+	api := r.Mount("/api")
+	api.HandleFunc("GET example.com/items", ok)
+
+	if err := r.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	want := []Route{
+		{Method: "GET", Host: "example.com", Path: "/api/items", Mount: "/api", Handler: "router.ok"},
+		{Method: "GET", Path: "/items", Handler: "router.ok"},
+		{Method: "GET", Host: "example.com", Path: "/items", Handler: "router.ok"},
+	}
+	if got := r.Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %+v, want %+v", got, want)
+	}
+	if got := want[0].String(); got != "GET example.com/api/items (mounted at /api)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestDebugRoutes(t *testing.T) {
+	r := New()
+	v1 := r.Mount("/v1", noop)
+	v1.Deprecate(Deprecation{})
+	v1.HandleFunc("GET /items/{id}", ok)
+	r.HandleFunc("/health", ok)
+
+	rec := httptest.NewRecorder()
+	r.DebugRoutes(rec, httptest.NewRequest("GET", "/debug/routes", nil))
+
+	var routes []Route
+	if err := json.NewDecoder(rec.Body).Decode(&routes); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || !reflect.DeepEqual(routes, r.Routes()) {
+		t.Errorf("json routes = %+v", routes)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/debug/routes?format=text", nil),
+		httptest.NewRequest("GET", "/debug/routes", nil),
+	} {
+		req.Header.Set("Accept", "text/plain")
+		rec := httptest.NewRecorder()
+		r.DebugRoutes(rec, req)
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "METHOD") ||
+			strings.Join(strings.Fields(lines[1]), " ") != "* /health - - router.ok" ||
+			strings.Join(strings.Fields(lines[2]), " ") != "GET /v1/items/{id} (deprecated) /v1 router.noop router.ok" {
+			t.Errorf("text routes:\n%v", rec.Body.String())
+		}
+	}
+}
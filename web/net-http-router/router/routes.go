@@ -0,0 +1,180 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Route describes a registered pattern as clients see it
+type Route struct {
+	Method string `json:"method,omitempty"` // empty when any method matches
+	Host   string `json:"host,omitempty"`   // empty when any host matches
+	// Path is the full path, including the prefixes of the sub routers
+	Path  string `json:"path"`
+	Mount string `json:"mount,omitempty"`
+	// Middlewares run in order before the handler, the ones of the outer
+	// sub routers first
+	Middlewares []string `json:"middlewares,omitempty"`
+	Deprecated  bool     `json:"deprecated,omitempty"`
+	Handler     string   `json:"handler"`
+}
+
+// route is a pattern as registered on the mux of a sub router
+type route struct {
+	pattern string
+	method  string
+	host    string
+	path    string
+	handler string
+}
+
+// newRoute splits a pattern of the form [METHOD ][HOST]/[PATH]
+func newRoute(pattern string, handler string) route {
+	r := route{pattern: pattern, path: pattern, handler: handler}
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		r.method, r.path = method, strings.TrimLeft(path, " \t")
+	}
+	if i := strings.Index(r.path, "/"); i > 0 {
+		r.host, r.path = r.path[:i], r.path[i:]
+	}
+	return r
+}
+
+// Routes lists every registered route, sorted by path then method
+func (r *Router) Routes() []Route {
+	var routes []Route
+	r.root.walk(func(s *SubRouter, rt route) {
+		routes = append(routes, s.describe(rt))
+	})
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// Validate fails when a route can't be reached because a pattern of an
+// outer mux, or of another sub router, takes its requests. Conflicts within
+// a single mux already panic when the route is registered.
+func (r *Router) Validate() error {
+	var errs []error
+	r.root.walk(func(s *SubRouter, rt route) {
+		target, pattern := r.resolve(sampleRequest(s, rt))
+		if target != s || pattern != rt.pattern {
+			errs = append(errs, fmt.Errorf("route %v is shadowed by %v", s.describe(rt), describePattern(target, pattern)))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// resolve finds the sub router and pattern serving req, the way the muxes
+// and StripPrefix do
+func (r *Router) resolve(req *http.Request) (*SubRouter, string) {
+	s := r.root
+	for {
+		_, pattern := s.mux.Handler(req)
+		sub, ok := s.mounts[pattern]
+		if !ok {
+			return s, pattern
+		}
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, sub.prefix)
+		s = sub
+	}
+}
+
+// sampleRequest builds a request matching the route, wildcards are filled
+// with a value unlikely to match a literal segment of another pattern
+func sampleRequest(s *SubRouter, rt route) *http.Request {
+	var segments []string
+	for _, segment := range strings.Split(rt.path, "/") {
+		switch {
+		case segment == "{$}":
+			segment = ""
+		case strings.HasPrefix(segment, "{"):
+			segment = "_"
+		}
+		segments = append(segments, segment)
+	}
+
+	method := rt.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req := httptest.NewRequest(method, s.Prefix()+strings.Join(segments, "/"), nil)
+	// routes without a host must not be matched by patterns with one
+	req.Host = rt.host
+	return req
+}
+
+func (s *SubRouter) walk(fn func(*SubRouter, route)) {
+	for _, rt := range s.routes {
+		fn(s, rt)
+	}
+
+	patterns := make([]string, 0, len(s.mounts))
+	for pattern := range s.mounts {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		s.mounts[pattern].walk(fn)
+	}
+}
+
+func (s *SubRouter) describe(rt route) Route {
+	route := Route{Method: rt.method, Host: rt.host, Path: s.Prefix() + rt.path, Mount: s.Prefix(), Handler: rt.handler}
+
+	var chain []*SubRouter
+	for sub := s; sub != nil; sub = sub.parent {
+		chain = append([]*SubRouter{sub}, chain...)
+	}
+	for _, sub := range chain {
+		for _, mw := range sub.middlewares {
+			route.Middlewares = append(route.Middlewares, funcName(mw))
+		}
+		route.Deprecated = route.Deprecated || sub.deprecation != nil
+	}
+	return route
+}
+
+// describePattern names what serves the requests of a shadowed route: a
+// pattern, or a sub router without a matching pattern
+func describePattern(s *SubRouter, pattern string) string {
+	if pattern == "" {
+		return "the mount " + s.Prefix()
+	}
+	return s.describe(newRoute(pattern, "")).String()
+}
+
+// String returns the full pattern and the mount of the route
+func (r Route) String() string {
+	pattern := strings.TrimSpace(r.Method + " " + r.Host + r.Path)
+	if r.Mount == "" {
+		return pattern
+	}
+	return fmt.Sprintf("%v (mounted at %v)", pattern, r.Mount)
+}
+
+// funcName names a handler or middleware by its function, eg: handlers.SimpleHandler
+func funcName(v interface{}) string {
+	if h, ok := v.(http.HandlerFunc); ok {
+		v = (func(http.ResponseWriter, *http.Request))(h)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", v)
+	}
+	// method values are suffixed with -fm
+	name := strings.TrimSuffix(runtime.FuncForPC(rv.Pointer()).Name(), "-fm")
+	return name[strings.LastIndex(name, "/")+1:]
+}
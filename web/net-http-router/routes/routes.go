@@ -1,25 +1,64 @@
 package routes
 
 import (
+	"context"
 	"log"
 	"net-http-router/handlers"
 	"net-http-router/middleware"
-	"net/http"
+	"net-http-router/router"
+	"net-http-router/server"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Serve() {
-	router := http.NewServeMux()
-	router.HandleFunc("GET /demo", handlers.SimpleHandler)
-	router.HandleFunc("GET /demo/{id}", handlers.PathParamHandler)
-	router.HandleFunc("POST /demo", handlers.PostBodyHandler)
+// v1 is kept for existing clients until its sunset, new clients use v2
+var v1Deprecation = router.Deprecation{
+	Since:     time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	Sunset:    time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC),
+	Successor: "/api/v2",
+}
+
+// NewRouter registers the routes, debug adds the route table at
+// /debug/routes: it lists every handler and middleware, keep it out of
+// production
+func NewRouter(debug bool) *router.Router {
+	r := router.New()
+
+	v1 := r.Mount("/api/v1")
+	v1.Deprecate(v1Deprecation)
+	registerDemoRoutes(v1)
+
+	v2 := r.Mount("/api/v2", middleware.Logger)
+	registerDemoRoutes(v2)
+
+	logged := r.Mount("/logger", middleware.Logger)
+	logged.HandleFunc("GET /logged", handlers.LoggedSubRouterHandler)
+
+	if debug {
+		r.HandleFunc("GET /debug/routes", r.DebugRoutes)
+	}
+	return r
+}
+
+func registerDemoRoutes(api *router.SubRouter) {
+	api.HandleFunc("GET /hello", handlers.QueryParamsHandler)
+	api.HandleFunc("GET /demo", handlers.SimpleHandler)
+	api.HandleFunc("GET /demo/{id}", handlers.PathParamHandler)
+	api.HandleFunc("POST /demo", handlers.PostBodyHandler)
+}
 
-	subrouter := http.NewServeMux()
+func Serve(opts server.Options, debug bool) {
+	r := NewRouter(debug)
+	if err := r.Validate(); err != nil {
+		log.Fatalf("invalid routes:\n%v", err)
+	}
 
-	subrouter.Handle("/api/v1/", http.StripPrefix("/api/v1", router))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	loggedSubRouter := http.NewServeMux()
-	loggedSubRouter.HandleFunc("GET /logger/logged", handlers.LoggedSubRouterHandler)
-	router.Handle("/", middleware.Logger(loggedSubRouter))
-	log.Println("server is running on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	if err := server.Run(ctx, r, opts); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,94 @@
+package routes_test
+
+import (
+	"net-http-router/routes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutesResolve(t *testing.T) {
+	r := routes.NewRouter(false)
+
+	tests := []struct {
+		method     string
+		path       string
+		status     int
+		deprecated bool
+	}{
+		{"GET", "/api/v1/demo", http.StatusOK, true},
+		{"GET", "/api/v1/demo/42", http.StatusOK, true},
+		{"POST", "/api/v1/demo", http.StatusOK, true},
+		{"GET", "/api/v2/demo", http.StatusOK, false},
+		{"GET", "/api/v2/demo/42", http.StatusOK, false},
+		{"POST", "/api/v2/demo", http.StatusOK, false},
+		{"GET", "/logger/logged", http.StatusOK, false},
+		{"GET", "/api/v2/hello?name=go", http.StatusOK, false},
+
+		{"GET", "/api/v2/hello", http.StatusBadRequest, false},
+		{"GET", "/api/v2/demo/abc", http.StatusBadRequest, false},
+
+		{"GET", "/api/v1/unknown", http.StatusNotFound, true},
+		{"DELETE", "/api/v1/demo", http.StatusMethodNotAllowed, true},
+		{"GET", "/api/v3/demo", http.StatusNotFound, false},
+		{"GET", "/demo", http.StatusNotFound, false},
+		{"GET", "/logged", http.StatusNotFound, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(`{"title":"demo"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("status = %v, want %v", rec.Code, test.status)
+			}
+			if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != test.deprecated {
+				t.Errorf("deprecated = %v, want %v", deprecated, test.deprecated)
+			}
+		})
+	}
+}
+
+func TestPathParamsUnderPrefix(t *testing.T) {
+	rec := httptest.NewRecorder()
+	routes.NewRouter(false).ServeHTTP(rec, httptest.NewRequest("GET", "/api/v2/demo/42?name=go", nil))
+
+	if body := rec.Body.String(); !strings.Contains(body, `"id":42`) {
+		t.Errorf("body = %v, want the id path value", body)
+	}
+}
+
+func TestPrefixRedirect(t *testing.T) {
+	rec := httptest.NewRecorder()
+	routes.NewRouter(false).ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1", nil))
+
+	// the status of the redirect depends on the go version
+	if location := rec.Header().Get("Location"); location != "/api/v1/" {
+		t.Errorf("Location = %q, want /api/v1/", location)
+	}
+}
+
+func TestRoutesAreReachable(t *testing.T) {
+	if err := routes.NewRouter(false).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDebugRoutesIsOptIn(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		routes.NewRouter(debug).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/routes", nil))
+
+		want := http.StatusNotFound
+		if debug {
+			want = http.StatusOK
+		}
+		if rec.Code != want {
+			t.Errorf("debug %v: status = %v, want %v", debug, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caCertFile   = "ca.pem"
+	caKeyFile    = "ca-key.pem"
+	leafCertFile = "cert.pem"
+	leafKeyFile  = "key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour // the longest validity browsers accept
+	// renewBefore regenerates a cached certificate that expires soon
+	renewBefore = 7 * 24 * time.Hour
+)
+
+// DevCertificate returns a certificate for localhost, the loopback addresses
+// and hosts, signed by a local CA. Both are generated on the first call and
+// cached in dir: add dir/ca.pem to the trusted roots of a browser to avoid
+// warnings. The leaf is regenerated when it expires or misses a host.
+func DevCertificate(dir string, hosts []string) (tls.Certificate, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return tls.Certificate{}, err
+	}
+	hosts = append([]string{"localhost", "127.0.0.1", "::1"}, hosts...)
+
+	ca, caKey, err := loadPair(dir, caCertFile, caKeyFile)
+	if err != nil {
+		if ca, caKey, err = createCA(dir); err != nil {
+			return tls.Certificate{}, fmt.Errorf("dev certificate: %w", err)
+		}
+		log.Printf("created a dev CA, trust %v to avoid certificate warnings", filepath.Join(dir, caCertFile))
+	}
+
+	leaf, _, err := loadPair(dir, leafCertFile, leafKeyFile)
+	if err != nil || !validLeaf(leaf, ca, hosts) {
+		if err := createLeaf(dir, ca, caKey, hosts); err != nil {
+			return tls.Certificate{}, fmt.Errorf("dev certificate: %w", err)
+		}
+	}
+
+	return tls.LoadX509KeyPair(filepath.Join(dir, leafCertFile), filepath.Join(dir, leafKeyFile))
+}
+
+func validLeaf(leaf *x509.Certificate, ca *x509.Certificate, hosts []string) bool {
+	if leaf.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if leaf.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func createCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "net-http-router dev CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	return createPair(dir, caCertFile, caKeyFile, template, nil, nil)
+}
+
+func createLeaf(dir string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts []string) error {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: hosts[0]},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	_, _, err := createPair(dir, leafCertFile, leafKeyFile, template, ca, caKey)
+	return err
+}
+
+// createPair signs template with parent (itself if nil) and writes the
+// certificate and its new key as pem files
+func createPair(dir string, certFile string, keyFile string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := writePem(filepath.Join(dir, keyFile), "EC PRIVATE KEY", keyDer, 0o600); err != nil {
+		return nil, nil, err
+	}
+	if err := writePem(filepath.Join(dir, certFile), "CERTIFICATE", der, 0o644); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	return cert, key, err
+}
+
+// loadPair reads a certificate and its key, it fails if they don't match or
+// the certificate is not valid for renewBefore anymore, to create a new pair
+func loadPair(dir string, certFile string, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, certFile), filepath.Join(dir, keyFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%v: not an ECDSA key", keyFile)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || cert.NotAfter.Sub(now) < renewBefore {
+		return nil, nil, fmt.Errorf("%v: valid from %v to %v", certFile, cert.NotBefore, cert.NotAfter)
+	}
+	return cert, key, nil
+}
+
+func writePem(path string, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
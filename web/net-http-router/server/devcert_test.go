@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDevCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	cert, err := DevCertificate(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPem, err := os.ReadFile(filepath.Join(dir, caCertFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPem)
+
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("%v: %v", host, err)
+		}
+	}
+
+	// the cached certificate is reused
+	cached, err := DevCertificate(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cached.Certificate[0]) != string(cert.Certificate[0]) {
+		t.Error("the certificate was regenerated")
+	}
+
+	// a new host regenerates the leaf, signed by the same CA
+	renewed, err := DevCertificate(dir, []string{"api.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ = x509.ParseCertificate(renewed.Certificate[0])
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "api.test", Roots: roots}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDevCertificateStalePair(t *testing.T) {
+	tests := map[string]func(t *testing.T, dir string){
+		"mismatched key": func(t *testing.T, dir string) {
+			// the key of the CA next to the leaf certificate
+			key, _ := os.ReadFile(filepath.Join(dir, caKeyFile))
+			if err := os.WriteFile(filepath.Join(dir, leafKeyFile), key, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"expired": func(t *testing.T, dir string) {
+			ca, caKey, err := loadPair(dir, caCertFile, caKeyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			template := &x509.Certificate{
+				Subject:   pkix.Name{CommonName: "localhost"},
+				NotBefore: time.Now().Add(-48 * time.Hour),
+				NotAfter:  time.Now().Add(-24 * time.Hour),
+				DNSNames:  []string{"localhost"},
+			}
+			if _, _, err := createPair(dir, leafCertFile, leafKeyFile, template, ca, caKey); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"corrupt": func(t *testing.T, dir string) {
+			os.WriteFile(filepath.Join(dir, leafCertFile), []byte("not a certificate"), 0o644)
+		},
+	}
+
+	for name, spoil := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := DevCertificate(dir, nil); err != nil {
+				t.Fatal(err)
+			}
+			spoil(t, dir)
+
+			// the stale pair is replaced instead of failing the handshakes
+			cert, err := DevCertificate(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+			if time.Until(leaf.NotAfter) < renewBefore {
+				t.Errorf("leaf expires %v", leaf.NotAfter)
+			}
+			if _, err := tls.LoadX509KeyPair(filepath.Join(dir, leafCertFile), filepath.Join(dir, leafKeyFile)); err != nil {
+				t.Errorf("pair on disk: %v", err)
+			}
+		})
+	}
+}
+
+func TestDevCertificateStaleCA(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := DevCertificate(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := os.ReadFile(filepath.Join(dir, leafKeyFile))
+	os.WriteFile(filepath.Join(dir, caKeyFile), key, 0o600)
+
+	// a new CA, and a leaf signed by it
+	cert, err := DevCertificate(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _, err := loadPair(dir, caCertFile, caKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	if err := leaf.CheckSignatureFrom(ca); err != nil {
+		t.Error(err)
+	}
+}
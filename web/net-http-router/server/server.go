@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Options configures the servers started by Run. Without TLS, Addr serves
+// plain HTTP. With TLS, TLSAddr serves HTTPS (and HTTP/2) while Addr serves
+// plain HTTP or redirects to HTTPS.
+type Options struct {
+	Addr    string
+	TLSAddr string
+
+	// TLS is enabled when both files are set, or with DevCertDir. Setting
+	// only one of them is an error.
+	CertFile string
+	KeyFile  string
+	// DevCertDir enables TLS with a self-signed certificate, generated and
+	// cached in the directory, see DevCertificate
+	DevCertDir string
+	// DevHosts are the names the dev certificate is valid for, besides
+	// localhost and the loopback addresses
+	DevHosts []string
+
+	// RedirectHTTP answers requests on Addr with a redirect to TLSAddr
+	RedirectHTTP bool
+	// H2C serves HTTP/2 without TLS on Addr, for internal traffic (eg:
+	// behind a proxy that terminates TLS). It can't be combined with
+	// RedirectHTTP, Addr then serves nothing but redirects.
+	H2C bool
+
+	ShutdownTimeout time.Duration
+}
+
+const (
+	defaultAddr            = ":8080"
+	defaultTLSAddr         = ":8443"
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+func (o Options) withDefaults() Options {
+	if o.Addr == "" {
+		o.Addr = defaultAddr
+	}
+	if o.TLSAddr == "" {
+		o.TLSAddr = defaultTLSAddr
+	}
+	if o.ShutdownTimeout == 0 {
+		o.ShutdownTimeout = defaultShutdownTimeout
+	}
+	return o
+}
+
+func (o Options) tls() bool {
+	return (o.CertFile != "" && o.KeyFile != "") || o.DevCertDir != ""
+}
+
+func (o Options) validate() error {
+	switch {
+	case (o.CertFile == "") != (o.KeyFile == ""):
+		return errors.New("server: tls needs both a certificate and a key file")
+	case o.RedirectHTTP && !o.tls():
+		return errors.New("server: redirecting to https needs a certificate")
+	case o.RedirectHTTP && o.H2C:
+		return errors.New("server: h2c can't be served when plain http redirects to https")
+	}
+	return nil
+}
+
+// Run serves handler until ctx is done, then shuts the servers down gracefully
+func Run(ctx context.Context, handler http.Handler, opts Options) error {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	var servers []*http.Server
+	serveErr := make(chan error, 2)
+
+	if opts.tls() {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return err
+		}
+		// ServeTLS enables HTTP/2 through ALPN
+		srv := &http.Server{Addr: opts.TLSAddr, Handler: handler, TLSConfig: tlsConfig, ReadHeaderTimeout: 5 * time.Second}
+		servers = append(servers, srv)
+		go func() {
+			log.Printf("server is running on %v (tls)", opts.TLSAddr)
+			serveErr <- srv.ListenAndServeTLS("", "")
+		}()
+	}
+
+	plain := handler
+	if opts.RedirectHTTP {
+		plain = redirectHandler(opts.TLSAddr)
+	} else if opts.H2C {
+		plain = h2c.NewHandler(plain, &http2.Server{})
+	}
+	srv := &http.Server{Addr: opts.Addr, Handler: plain, ReadHeaderTimeout: 5 * time.Second}
+	servers = append(servers, srv)
+	go func() {
+		log.Printf("server is running on %v", opts.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// a server could not start (eg: port in use), stop the other one
+	case <-ctx.Done():
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+			// deadline exceeded, drop the remaining connections
+			srv.Close()
+			err = errors.Join(err, shutdownErr)
+		}
+	}
+	return err
+}
+
+func (o Options) tlsConfig() (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if o.CertFile != "" && o.KeyFile != "" {
+		cert, err = tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	} else {
+		cert, err = DevCertificate(o.DevCertDir, o.DevHosts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// redirectHandler sends clients to the same url on the https server
+func redirectHandler(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		} else {
+			// an IPv6 literal without a port, eg: [::1]
+			host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		url := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, url, http.StatusPermanentRedirect)
+	})
+}
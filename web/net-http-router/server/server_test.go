@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		tlsAddr string
+		host    string
+		want    string
+	}{
+		{":8443", "example.com:8080", "https://example.com:8443/a?b=c"},
+		{":8443", "example.com", "https://example.com:8443/a?b=c"},
+		{":443", "example.com:80", "https://example.com/a?b=c"},
+		{":8443", "[::1]:8080", "https://[::1]:8443/a?b=c"},
+		{":8443", "[::1]", "https://[::1]:8443/a?b=c"},
+		{":443", "[::1]", "https://[::1]/a?b=c"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/a?b=c", nil)
+		req.Host = test.host
+		rec := httptest.NewRecorder()
+		redirectHandler(test.tlsAddr).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != test.want {
+			t.Errorf("%v on %v: %v %q, want %q", test.host, test.tlsAddr, rec.Code, rec.Header().Get("Location"), test.want)
+		}
+	}
+}
+
+func TestRunInvalidOptions(t *testing.T) {
+	tests := map[string]Options{
+		"cert without key":       {CertFile: "cert.pem"},
+		"key without cert":       {KeyFile: "key.pem"},
+		"redirect without tls":   {RedirectHTTP: true},
+		"redirect and h2c":       {DevCertDir: t.TempDir(), RedirectHTTP: true, H2C: true},
+		"missing certificate":    {CertFile: "missing.pem", KeyFile: "missing.pem"},
+		"address already in use": {Addr: listening(t)},
+	}
+	for name, opts := range tests {
+		done := make(chan error, 1)
+		go func() { done <- Run(context.Background(), http.NotFoundHandler(), opts) }()
+
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Errorf("%v: Run returned nil", name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v: Run is serving", name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	addr, tlsAddr := freeAddr(t), freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}), Options{Addr: addr, TLSAddr: tlsAddr, DevCertDir: t.TempDir(), RedirectHTTP: true})
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	var resp *http.Response
+	var err error
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(20 * time.Millisecond) {
+		if resp, err = client.Get("http://" + addr + "/ping"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusPermanentRedirect || !strings.HasPrefix(location, "https://") {
+		t.Fatalf("plain http: %v, Location %q", resp.StatusCode, location)
+	}
+
+	resp, err = client.Get("https://" + tlsAddr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("https proto = %v, want HTTP/2", resp.Proto)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v after a graceful shutdown", err)
+	}
+}
+
+// freeAddr returns a loopback address with a port nothing listens on
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// listening returns the address of a listener open until the test ends
+func listening(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l.Addr().String()
+}